@@ -0,0 +1,572 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Soontao/hanafs/hana"
+	"github.com/urfave/cli"
+)
+
+// exit codes of commands
+const (
+	exitCodeError      = 1
+	exitCodeUsage      = 2
+	exitCodeNotFound   = 3
+	exitCodePermission = 4
+	exitCodeConflict   = 5
+	exitCodeTimeout    = 6
+)
+
+// exitError with the exit code of error kind
+func exitError(err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(cli.ExitCoder); ok {
+		return err
+	}
+
+	code := exitCodeError
+
+	switch {
+	case hana.IsNotFound(err):
+		code = exitCodeNotFound
+	case hana.IsForbidden(err), hana.IsUnauthorized(err):
+		code = exitCodePermission
+	case hana.IsConflict(err):
+		code = exitCodeConflict
+	case hana.IsTimeout(err):
+		code = exitCodeTimeout
+	}
+
+	return cli.NewExitError(err, code)
+}
+
+// withClient run the command action with hana client, the error will be converted to exit code
+func withClient(args int, action func(c *cli.Context, client *hana.Client) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+
+		if c.NArg() < args {
+			cli.ShowCommandHelp(c, c.Command.Name)
+			return cli.NewExitError("", exitCodeUsage)
+		}
+
+		if err := loadProfile(c); err != nil {
+			return exitError(err)
+		}
+
+		client, _, err := newClient(c)
+
+		if err != nil {
+			return exitError(err)
+		}
+
+		return exitError(action(c, client))
+	}
+}
+
+// remotePath of argument, absolute path under base directory
+func remotePath(p string) string {
+	return path.Clean("/" + filepath.ToSlash(p))
+}
+
+// printJSON to stdout
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+var jsonFlag = cli.BoolFlag{
+	Name:  "json",
+	Usage: "Output as json",
+}
+
+// listEntry of ls command
+type listEntry struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Directory bool   `json:"directory"`
+	ReadOnly  bool   `json:"readOnly"`
+	Activated bool   `json:"activated"`
+}
+
+func listAction(c *cli.Context, client *hana.Client) error {
+
+	dir := remotePath(c.Args().First())
+
+	stat, err := client.Stat(dir)
+
+	if err != nil {
+		return err
+	}
+
+	entries := []*listEntry{}
+
+	if stat.Directory {
+		detail, err := client.ReadDirectory(dir, 1)
+		if err != nil {
+			return err
+		}
+		for _, child := range detail.Children {
+			entries = append(entries, &listEntry{
+				Name:      child.Name,
+				Path:      path.Join(dir, child.Name),
+				Directory: child.Directory,
+				ReadOnly:  child.Attributes.ReadOnly,
+				Activated: child.Attributes.SapBackPack.Activated,
+			})
+		}
+	} else {
+		// the path is a file
+		entries = append(entries, &listEntry{
+			Name:      path.Base(dir),
+			Path:      dir,
+			ReadOnly:  stat.ReadOnly,
+			Activated: stat.Activated,
+		})
+	}
+
+	if c.Bool("json") {
+		return printJSON(entries)
+	}
+
+	for _, entry := range entries {
+		if entry.Directory {
+			fmt.Println(entry.Name + "/")
+		} else {
+			fmt.Println(entry.Name)
+		}
+	}
+
+	return nil
+}
+
+func catAction(c *cli.Context, client *hana.Client) error {
+
+	for _, p := range c.Args() {
+		content, err := client.ReadFile(remotePath(p))
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stdout.Write(content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getAction(c *cli.Context, client *hana.Client) error {
+
+	remote := remotePath(c.Args().Get(0))
+	local := c.Args().Get(1)
+
+	if len(local) == 0 {
+		local = path.Base(remote)
+	}
+
+	content, err := client.ReadFile(remote)
+
+	if err != nil {
+		return err
+	}
+
+	if local == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+
+	if info, err := os.Stat(local); err == nil && info.IsDir() {
+		local = filepath.Join(local, path.Base(remote))
+	}
+
+	return ioutil.WriteFile(local, content, 0644)
+}
+
+// createFile with empty content
+func createFile(client *hana.Client, remote string) error {
+	base, name := path.Split(remote)
+	return client.Create(base, name, false)
+}
+
+func putAction(c *cli.Context, client *hana.Client) error {
+
+	local := c.Args().Get(0)
+	remote := remotePath(c.Args().Get(1))
+
+	var content []byte
+	var err error
+
+	if local == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(local)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	stat, err := client.Stat(remote)
+
+	switch {
+	case err == nil && stat.Directory:
+		if local == "-" {
+			return fmt.Errorf("'%v' is a directory", remote)
+		}
+		remote = path.Join(remote, filepath.Base(local))
+		if _, err := client.Stat(remote); hana.IsNotFound(err) {
+			if err := createFile(client, remote); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	case hana.IsNotFound(err):
+		if err := createFile(client, remote); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	if err := client.WriteFileContent(remote, content); err != nil {
+		return err
+	}
+
+	if c.Bool("activate") {
+		return client.Activate(remote)
+	}
+
+	return nil
+}
+
+func removeAction(c *cli.Context, client *hana.Client) error {
+
+	for _, p := range c.Args() {
+		if err := client.Delete(remotePath(p)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func moveAction(c *cli.Context, client *hana.Client) error {
+
+	old := remotePath(c.Args().Get(0))
+	new := remotePath(c.Args().Get(1))
+
+	stat, err := client.Stat(old)
+
+	if err != nil {
+		return err
+	}
+
+	// move into the existed directory
+	if target, err := client.Stat(new); err == nil && target.Directory {
+		new = path.Join(new, path.Base(old))
+	} else if err != nil && !hana.IsNotFound(err) {
+		return err
+	}
+
+	// the object is copied & deleted between packages, it would not overwrite the target
+	if _, err := client.Stat(new); err == nil {
+		return cli.NewExitError(fmt.Sprintf("'%v' already exists", new), exitCodeConflict)
+	} else if !hana.IsNotFound(err) {
+		return err
+	}
+
+	return hana.Move(context.Background(), client, old, new, stat.Directory)
+}
+
+func mkdirAction(c *cli.Context, client *hana.Client) error {
+
+	for _, p := range c.Args() {
+		dir := remotePath(p)
+		base, name := path.Split(dir)
+		if err := client.Create(base, name, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func statAction(c *cli.Context, client *hana.Client) error {
+
+	stat, err := client.Stat(remotePath(c.Args().First()))
+
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		return printJSON(newStatOutput(remotePath(c.Args().First()), stat))
+	}
+
+	printStat(os.Stdout, remotePath(c.Args().First()), stat)
+
+	return nil
+}
+
+// statOutput of stat command in json
+type statOutput struct {
+	Path         string `json:"path"`
+	Directory    bool   `json:"directory"`
+	ReadOnly     bool   `json:"readOnly"`
+	Executable   bool   `json:"executable"`
+	Hidden       bool   `json:"hidden"`
+	Activated    bool   `json:"activated"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag,omitempty"`
+	Version      int64  `json:"version,omitempty"`
+	Modified     string `json:"modified,omitempty"`
+	ActivatedBy  string `json:"activatedBy,omitempty"`
+	ObjectStatus string `json:"objectStatus,omitempty"`
+	ContentType  string `json:"contentType,omitempty"`
+}
+
+func newStatOutput(p string, stat *hana.PathStat) *statOutput {
+
+	rt := &statOutput{
+		Path:         p,
+		Directory:    stat.Directory,
+		ReadOnly:     stat.ReadOnly,
+		Executable:   stat.Executable,
+		Hidden:       stat.Hidden,
+		Activated:    stat.Activated,
+		Size:         stat.Size,
+		ETag:         stat.ETag,
+		Version:      stat.Version,
+		ActivatedBy:  stat.ActivatedBy,
+		ObjectStatus: stat.ObjectStatus,
+		ContentType:  stat.ContentType,
+	}
+
+	if stat.TimeStamp > 0 {
+		rt.Modified = time.Unix(stat.TimeStamp/1000, 0).Format(time.RFC3339)
+	}
+
+	return rt
+}
+
+// printStat in human readable format
+func printStat(w io.Writer, p string, stat *hana.PathStat) {
+
+	kind := "file"
+
+	if stat.Directory {
+		kind = "directory"
+	}
+
+	fmt.Fprintf(w, "Path:      %v\n", p)
+	fmt.Fprintf(w, "Type:      %v\n", kind)
+
+	if stat.Directory {
+		return
+	}
+
+	fmt.Fprintf(w, "Size:      %v\n", stat.Size)
+	fmt.Fprintf(w, "ETag:      %v\n", stat.ETag)
+	fmt.Fprintf(w, "Version:   %v\n", stat.Version)
+	fmt.Fprintf(w, "Activated: %v\n", stat.Activated)
+
+	if stat.TimeStamp > 0 {
+		fmt.Fprintf(w, "Modified:  %v\n", time.Unix(stat.TimeStamp/1000, 0).Format(time.RFC3339))
+	}
+
+	if len(stat.ActivatedBy) > 0 {
+		fmt.Fprintf(w, "Author:    %v\n", stat.ActivatedBy)
+	}
+
+	if len(stat.ObjectStatus) > 0 {
+		fmt.Fprintf(w, "Status:    %v\n", stat.ObjectStatus)
+	}
+
+}
+
+func unmountAction(c *cli.Context) error {
+
+	if c.NArg() < 1 {
+		cli.ShowCommandHelp(c, c.Command.Name)
+		return cli.NewExitError("", exitCodeUsage)
+	}
+
+	mountpoint := absMountPoint(c.Args().First())
+
+	record, err := findMountRecord(mountpoint)
+
+	if err != nil {
+		return exitError(err)
+	}
+
+	if record != nil && processAlive(record.PID) {
+
+		if err := terminateProcess(record.PID); err != nil {
+			return exitError(err)
+		}
+
+		deadline := time.Now().Add(c.Duration("wait"))
+
+		// the process drain the buffers before exit
+		for processAlive(record.PID) {
+			if time.Now().After(deadline) {
+				return cli.NewExitError(fmt.Sprintf("process %v of '%v' is still running after %v", record.PID, mountpoint, c.Duration("wait")), exitCodeTimeout)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		// the record is left if the process is killed
+		removeMountRecord(mountpoint)
+
+		log.Printf("unmounted '%v'", mountpoint)
+
+		return nil
+	}
+
+	// the owner process is gone, e.g. killed, remove the stale mount
+	if record != nil {
+		removeMountRecord(mountpoint)
+	}
+
+	if out, err := systemUnmount(mountpoint); err != nil {
+		return cli.NewExitError(strings.TrimSpace(fmt.Sprintf("unmount '%v' failed: %v %s", mountpoint, err, out)), exitCodeError)
+	}
+
+	log.Printf("unmounted stale mount point '%v'", mountpoint)
+
+	return nil
+}
+
+func statusAction(c *cli.Context) error {
+
+	records, err := listMountRecords()
+
+	if err != nil {
+		return exitError(err)
+	}
+
+	if c.Bool("json") {
+		if records == nil {
+			records = []*mountRecord{}
+		}
+		return exitError(printJSON(records))
+	}
+
+	if len(records) == 0 {
+		fmt.Println("no active mounts")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "MOUNT POINT\tPID\tHOST\tBASE\tSTARTED")
+
+	for _, r := range records {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", r.MountPoint, r.PID, r.Host, r.Base, r.Started.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+// commands of application, the connection options are global flags
+func commands() []cli.Command {
+	return []cli.Command{
+		{
+			Name:   "mount",
+			Usage:  "Mount the hana repository as file system (default)",
+			Action: mountAction,
+		},
+		{
+			Name:      "unmount",
+			Aliases:   []string{"umount"},
+			Usage:     "Unmount a running hanafs mount, or a stale mount point",
+			ArgsUsage: "mountpoint",
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "wait",
+					Usage: "Wait the mount process drain buffers and exit",
+					Value: 30 * time.Second,
+				},
+			},
+			Action: unmountAction,
+		},
+		{
+			Name:   "status",
+			Usage:  "List active hanafs mounts",
+			Flags:  []cli.Flag{jsonFlag},
+			Action: statusAction,
+		},
+		{
+			Name:      "ls",
+			Usage:     "List directory",
+			ArgsUsage: "[path]",
+			Flags:     []cli.Flag{jsonFlag},
+			Action:    withClient(0, listAction),
+		},
+		{
+			Name:      "cat",
+			Usage:     "Print file content",
+			ArgsUsage: "path...",
+			Action:    withClient(1, catAction),
+		},
+		{
+			Name:      "get",
+			Usage:     "Download file, '-' for stdout",
+			ArgsUsage: "remote [local]",
+			Action:    withClient(1, getAction),
+		},
+		{
+			Name:      "put",
+			Usage:     "Upload file, '-' for stdin, the remote file will be created if not existed",
+			ArgsUsage: "local remote",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "activate",
+					Usage: "Activate the object after upload",
+				},
+			},
+			Action: withClient(2, putAction),
+		},
+		{
+			Name:      "rm",
+			Usage:     "Remove files or directories",
+			ArgsUsage: "path...",
+			Action:    withClient(1, removeAction),
+		},
+		{
+			Name:      "mv",
+			Usage:     "Move or rename file or directory, across packages by copy & delete",
+			ArgsUsage: "old new",
+			Action:    withClient(2, moveAction),
+		},
+		{
+			Name:      "mkdir",
+			Usage:     "Create directories",
+			ArgsUsage: "path...",
+			Action:    withClient(1, mkdirAction),
+		},
+		{
+			Name:      "stat",
+			Usage:     "Print file or directory metadata",
+			ArgsUsage: "path",
+			Flags:     []cli.Flag{jsonFlag},
+			Action:    withClient(1, statAction),
+		},
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// ConfigFileName under the hanafs config directory
+const ConfigFileName = "config.toml"
+
+// DefaultProfileName is used if no profile selected and no profile of host
+const DefaultProfileName = "default"
+
+// Profile of config, key -> value
+type Profile map[string]string
+
+// Get value of key, empty if not set
+func (p Profile) Get(key string) string {
+	if p == nil {
+		return ""
+	}
+	return p[key]
+}
+
+// Config of hanafs
+//
+// a subset of toml, each table is a named profile:
+//
+//	[dev]
+//	host = "dev.hana.example.com"
+//	user = "DEVELOPER"
+//	password-command = "pass show hana/dev"
+type Config struct {
+	// profile names in file order
+	Names    []string
+	Profiles map[string]Profile
+}
+
+// ProfileByHost the first profile of host, nil if not found
+func (c *Config) ProfileByHost(host string) (string, Profile) {
+	for _, name := range c.Names {
+		p := c.Profiles[name]
+		if u, err := parseHost(p.Get("host")); err == nil && len(host) > 0 && u.Hostname() == host {
+			return name, p
+		}
+	}
+	return "", nil
+}
+
+// userConfigDir of current user
+func userConfigDir() (string, error) {
+
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("AppData"); len(dir) > 0 {
+			return dir, nil
+		}
+		return "", fmt.Errorf("%%AppData%% is not defined")
+	case "darwin":
+		if home := os.Getenv("HOME"); len(home) > 0 {
+			return filepath.Join(home, "Library", "Application Support"), nil
+		}
+		return "", fmt.Errorf("$HOME is not defined")
+	default:
+		if dir := os.Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+			return dir, nil
+		}
+		if home := os.Getenv("HOME"); len(home) > 0 {
+			return filepath.Join(home, ".config"), nil
+		}
+		return "", fmt.Errorf("neither $XDG_CONFIG_HOME nor $HOME are defined")
+	}
+
+}
+
+// defaultConfigPath of hanafs, empty if could not be found
+func defaultConfigPath() string {
+	dir, err := userConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hanafs", ConfigFileName)
+}
+
+// parseConfigValue of basic string, literal string or bare value, the tailing comment is ignored
+func parseConfigValue(raw string) (string, error) {
+
+	if !strings.HasPrefix(raw, `"`) && !strings.HasPrefix(raw, "'") {
+		if i := strings.Index(raw, "#"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+
+	quote := raw[0]
+	end := -1
+
+	for i := 1; i < len(raw); i++ {
+		if quote == '"' && raw[i] == '\\' {
+			// skip escaped char
+			i++
+			continue
+		}
+		if raw[i] == quote {
+			end = i
+			break
+		}
+	}
+
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string %v", raw)
+	}
+
+	if rest := strings.TrimSpace(raw[end+1:]); len(rest) > 0 && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %v after string", rest)
+	}
+
+	if quote == '\'' {
+		return raw[1:end], nil
+	}
+
+	return strconv.Unquote(raw[:end+1])
+}
+
+// LoadConfig from file, empty config if file not existed
+func LoadConfig(path string) (*Config, error) {
+
+	rt := &Config{Profiles: map[string]Profile{}}
+
+	if len(path) == 0 {
+		return rt, nil
+	}
+
+	f, err := os.Open(path)
+
+	if os.IsNotExist(err) {
+		return rt, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	var current Profile
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%v:%v: unterminated table", path, lineNo)
+			}
+			name := strings.Trim(strings.TrimSpace(line[1:end]), `"`)
+			if _, exist := rt.Profiles[name]; !exist {
+				rt.Names = append(rt.Names, name)
+				rt.Profiles[name] = Profile{}
+			}
+			current = rt.Profiles[name]
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%v:%v: key = value expected", path, lineNo)
+		}
+
+		if current == nil {
+			return nil, fmt.Errorf("%v:%v: key outside of profile", path, lineNo)
+		}
+
+		value, err := parseConfigValue(strings.TrimSpace(parts[1]))
+
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", path, lineNo, err)
+		}
+
+		current[strings.Trim(strings.TrimSpace(parts[0]), `"`)] = value
+
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+// selectProfile by --profile, or the profile of host, or the default profile
+//
+// nil if no profile found
+func selectProfile(c *cli.Context, config *Config) (string, Profile, error) {
+
+	if name := c.GlobalString("profile"); len(name) > 0 {
+		if p, exist := config.Profiles[name]; exist {
+			return name, p, nil
+		}
+		return "", nil, fmt.Errorf("profile '%v' not found in config file '%v'", name, c.GlobalString("config"))
+	}
+
+	if host := c.GlobalString("host"); len(host) > 0 {
+		if u, err := parseHost(host); err == nil {
+			if name, p := config.ProfileByHost(u.Hostname()); p != nil {
+				return name, p, nil
+			}
+		}
+		return "", nil, nil
+	}
+
+	if p, exist := config.Profiles[DefaultProfileName]; exist {
+		return DefaultProfileName, p, nil
+	}
+
+	return "", nil, nil
+}
+
+// applyProfile values to the flags which are not set by command line or env vars
+func applyProfile(c *cli.Context, profile Profile) error {
+
+	known := map[string]bool{}
+
+	for _, name := range c.GlobalFlagNames() {
+		known[name] = true
+	}
+
+	keys := []string{}
+
+	for key := range profile {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+
+		if !known[key] || key == "profile" || key == "config" {
+			return fmt.Errorf("unknown option '%v'", key)
+		}
+
+		if c.GlobalIsSet(key) {
+			continue
+		}
+
+		if err := c.GlobalSet(key, profile[key]); err != nil {
+			return fmt.Errorf("invalid value '%v' of '%v': %v", profile[key], key, err)
+		}
+
+	}
+
+	return nil
+}
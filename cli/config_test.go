@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigValue(t *testing.T) {
+	cases := []struct {
+		raw   string
+		value string
+		valid bool
+	}{
+		{`bare`, "bare", true},
+		{`bare # comment`, "bare", true},
+		{`"basic"`, "basic", true},
+		{`"with \"quote\" # not comment"`, `with "quote" # not comment`, true},
+		{`"tab\tand\\"`, "tab\tand\\", true},
+		{`'C:\literal\path'`, `C:\literal\path`, true},
+		{`"value" # comment`, "value", true},
+		{`"unterminated`, "", false},
+		{`"value" rest`, "", false},
+	}
+
+	for _, c := range cases {
+		value, err := parseConfigValue(c.raw)
+		if (err == nil) != c.valid || value != c.value {
+			t.Errorf("%v: got %q, %v", c.raw, value, err)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	p, clean := writeTempFile(t, "config.toml", `
+# hanafs profiles
+[default]
+host = "hana.example.com"
+user = DEVELOPER
+
+[dev]
+host = "https://dev.example.com:4300"
+"password-command" = 'pass show hana/dev' # helper
+
+[default]
+timeout = "30s"
+`)
+	defer clean()
+
+	config, err := LoadConfig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(config.Names, []string{"default", "dev"}) {
+		t.Errorf("names %v", config.Names)
+	}
+
+	expected := map[string]Profile{
+		"default": {"host": "hana.example.com", "user": "DEVELOPER", "timeout": "30s"},
+		"dev":     {"host": "https://dev.example.com:4300", "password-command": "pass show hana/dev"},
+	}
+
+	if !reflect.DeepEqual(config.Profiles, expected) {
+		t.Errorf("profiles %v", config.Profiles)
+	}
+
+	if name, _ := config.ProfileByHost("dev.example.com"); name != "dev" {
+		t.Errorf("profile of host %q", name)
+	}
+
+	if _, p := config.ProfileByHost("unknown.example.com"); p != nil || p.Get("host") != "" {
+		t.Errorf("no profile expected, got %v", p)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	cases := []struct {
+		content string
+		message string
+	}{
+		{"host = x", "key outside of profile"},
+		{"[dev\nhost = x", "unterminated table"},
+		{"[dev]\nhost", "key = value expected"},
+		{"[dev]\nhost = \"x", "unterminated string"},
+	}
+
+	for _, c := range cases {
+		p, clean := writeTempFile(t, "config.toml", c.content)
+		_, err := LoadConfig(p)
+		clean()
+		if err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("%q: error with %q expected, got %v", c.content, c.message, err)
+		}
+	}
+}
+
+func TestLoadConfigNotExisted(t *testing.T) {
+	for _, p := range []string{"", filepath.Join(os.TempDir(), "hanafs-not-existed", "config.toml")} {
+		config, err := LoadConfig(p)
+		if err != nil || len(config.Names) != 0 {
+			t.Errorf("'%v': empty config expected, got %v, %v", p, config, err)
+		}
+	}
+}
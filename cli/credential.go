@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Soontao/hanafs/hana"
+)
+
+// netrcEntry of machine
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// netrcPath of current user, $NETRC or ~/.netrc (~/_netrc on windows)
+func netrcPath() string {
+
+	if p := os.Getenv("NETRC"); len(p) > 0 {
+		return p
+	}
+
+	home := os.Getenv("HOME")
+
+	if runtime.GOOS == "windows" {
+		home = os.Getenv("USERPROFILE")
+		if len(home) > 0 {
+			return filepath.Join(home, "_netrc")
+		}
+	}
+
+	if len(home) == 0 {
+		return ""
+	}
+
+	return filepath.Join(home, ".netrc")
+}
+
+// lookupNetrc entry of machine, the 'default' entry will be used if the machine not found
+func lookupNetrc(path, machine string) (*netrcEntry, error) {
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var matched, fallback, current *netrcEntry
+
+	tokens := []string{}
+
+	// macdef body is ended by a blank line, and should be skipped
+	inMacro := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			inMacro = len(strings.TrimSpace(line)) > 0
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, f := range fields {
+			if f == "macdef" {
+				fields = fields[:i]
+				inMacro = true
+				break
+			}
+		}
+		tokens = append(tokens, fields...)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+
+		switch tokens[i] {
+		case "machine":
+			current = nil
+			if matched == nil && i+1 < len(tokens) && tokens[i+1] == machine {
+				matched = &netrcEntry{}
+				current = matched
+			}
+			i++
+		case "default":
+			current = nil
+			if fallback == nil {
+				fallback = &netrcEntry{}
+				current = fallback
+			}
+		case "login", "password", "account":
+			if i+1 < len(tokens) && current != nil {
+				if tokens[i] == "login" {
+					current.login = tokens[i+1]
+				}
+				if tokens[i] == "password" {
+					current.password = tokens[i+1]
+				}
+			}
+			i++
+		}
+
+	}
+
+	if matched != nil {
+		return matched, nil
+	}
+
+	return fallback, nil
+}
+
+// promptPassword from terminal without echo
+func promptPassword(user, host string) (string, error) {
+
+	fmt.Fprintf(os.Stderr, "Password for '%v@%v': ", user, host)
+
+	password, err := readPasswordNoEcho()
+
+	fmt.Fprintln(os.Stderr)
+
+	return password, err
+}
+
+// credential of basic authentication
+type credential struct {
+	user     string
+	password string
+	// where the password comes from, for logging
+	source string
+}
+
+// resolveCredential of host, the password is resolved by the chain:
+//
+// flag/env/profile -> password command -> netrc -> interactive prompt
+func resolveCredential(user, password, passwordCommand, host string) (*credential, error) {
+
+	rt := &credential{user: user, password: password, source: "flag or profile"}
+
+	if len(rt.password) > 0 {
+		return rt, nil
+	}
+
+	if len(passwordCommand) > 0 {
+		// the first line of stdout is the password
+		p, err := hana.RunCommand(context.Background(), passwordCommand)
+		if err != nil {
+			return nil, err
+		}
+		rt.password, rt.source = p, "password command"
+		return rt, nil
+	}
+
+	if path := netrcPath(); len(path) > 0 {
+		entry, err := lookupNetrc(path, host)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if entry != nil && (len(rt.user) == 0 || rt.user == entry.login) {
+			rt.user, rt.password, rt.source = entry.login, entry.password, "netrc"
+			return rt, nil
+		}
+	}
+
+	// the client certificate or other authentication maybe used without user
+	if len(rt.user) > 0 && stdinIsTerminal() {
+		p, err := promptPassword(rt.user, host)
+		if err != nil {
+			return nil, err
+		}
+		rt.password, rt.source = p, "prompt"
+	}
+
+	return rt, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, name, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "hanafs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return p, func() { os.RemoveAll(dir) }
+}
+
+const testNetrc = `
+machine other.example.com login other password other-secret
+
+machine hana.example.com
+  login DEVELOPER
+  password secret
+  account ignored
+
+macdef init
+machine hana.example.com login macro password macro
+
+default login anonymous password guest
+`
+
+func TestLookupNetrc(t *testing.T) {
+	p, clean := writeTempFile(t, ".netrc", testNetrc)
+	defer clean()
+
+	cases := []struct {
+		machine  string
+		login    string
+		password string
+	}{
+		{"hana.example.com", "DEVELOPER", "secret"},
+		{"other.example.com", "other", "other-secret"},
+		{"unknown.example.com", "anonymous", "guest"},
+	}
+
+	for _, c := range cases {
+		entry, err := lookupNetrc(p, c.machine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil || entry.login != c.login || entry.password != c.password {
+			t.Errorf("%v: got %+v", c.machine, entry)
+		}
+	}
+}
+
+func TestLookupNetrcWithoutDefault(t *testing.T) {
+	p, clean := writeTempFile(t, ".netrc", "machine hana.example.com login u password p\n")
+	defer clean()
+
+	entry, err := lookupNetrc(p, "unknown.example.com")
+	if err != nil || entry != nil {
+		t.Errorf("no entry expected, got %+v, %v", entry, err)
+	}
+
+	if _, err := lookupNetrc(p+".not-existed", "hana.example.com"); !os.IsNotExist(err) {
+		t.Errorf("not exist error expected, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// daemonChildEnv mark the process is the detached child of daemon
+const daemonChildEnv = "HANAFS_DAEMON_CHILD"
+
+// daemonStartTimeout of waiting the child mounted
+const daemonStartTimeout = 2 * time.Minute
+
+// isDaemonChild check current process is started by daemon parent
+func isDaemonChild() bool {
+	return os.Getenv(daemonChildEnv) == "1"
+}
+
+// writePIDFile of current process
+func writePIDFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)
+}
+
+// receivePassword from the daemon parent by stdin, the pipe is closed after read
+func receivePassword(c *cli.Context) error {
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	os.Stdin.Close()
+
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	if password = strings.TrimSuffix(password, "\n"); len(password) > 0 {
+		return c.GlobalSet("password", password)
+	}
+
+	return nil
+}
+
+// readFrom the file content after offset
+func readFrom(path string, offset int64) string {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return ""
+	}
+
+	defer f.Close()
+
+	if _, err := f.Seek(offset, 0); err != nil {
+		return ""
+	}
+
+	content, _ := ioutil.ReadAll(f)
+
+	return strings.TrimSpace(string(content))
+}
+
+// startDaemon validate the connection, start the detached child and wait it mounted
+//
+// the mount errors of child are reported from the log file
+func startDaemon(c *cli.Context) error {
+
+	// check the connection & resolve the credential in terminal
+	_, uri, err := newClient(c)
+
+	if err != nil {
+		return err
+	}
+
+	mountpoint, err := mountPoint(c, uri)
+
+	if err != nil {
+		return err
+	}
+
+	if err := checkMounted(mountpoint); err != nil {
+		return err
+	}
+
+	logFile := c.GlobalString("log-file")
+
+	if len(logFile) == 0 {
+		logFile = filepath.Join(runtimeDir(), mountID(mountpoint)+".log")
+	}
+
+	pidFile := c.GlobalString("pid-file")
+
+	if len(pidFile) == 0 {
+		pidFile = filepath.Join(runtimeDir(), mountID(mountpoint)+".pid")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logFile), 0700); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	offset, _ := out.Seek(0, 2)
+
+	executable, err := os.Executable()
+
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(
+		os.Environ(),
+		daemonChildEnv+"=1",
+		"HANAFS_LOG_FILE="+logFile,
+		"HANAFS_PID_FILE="+pidFile,
+		"HANA_USER="+uri.User.Username(),
+	)
+
+	// the password maybe resolved from prompt, pass it by pipe instead of
+	// environment, which is readable for the life of process
+	secret, err := cmd.StdinPipe()
+
+	if err != nil {
+		return err
+	}
+
+	// the early panic of child will be kept in log
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.SysProcAttr = daemonAttr()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	password, _ := uri.User.Password()
+
+	if _, err := io.WriteString(secret, password+"\n"); err != nil {
+		log.Printf("send password to daemon failed: %v", err)
+	}
+
+	secret.Close()
+
+	exited := make(chan error, 1)
+
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	deadline := time.After(daemonStartTimeout)
+
+	for {
+		select {
+		case err := <-exited:
+			if detail := readFrom(logFile, offset); len(detail) > 0 {
+				fmt.Fprintln(os.Stderr, detail)
+			}
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return fmt.Errorf("mount '%v' failed, daemon %v", mountpoint, err)
+		case <-deadline:
+			return fmt.Errorf("mount '%v' is not ready after %v, see log file '%v'", mountpoint, daemonStartTimeout, logFile)
+		case <-time.After(100 * time.Millisecond):
+			if record, err := findMountRecord(mountpoint); err == nil && record != nil && record.PID == cmd.Process.Pid {
+				log.Printf("mounted '%v' in background, pid: %v, log file: '%v'", mountpoint, record.PID, logFile)
+				return nil
+			}
+		}
+	}
+
+}
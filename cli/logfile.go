@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rotateWriter of log file, the file is rotated when the max size exceeded
+//
+// the rotated files are named as 'file.1' (latest), 'file.2' ...
+type rotateWriter struct {
+	lock     sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// open log file in append mode
+func (w *rotateWriter) open(flag int) error {
+
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|flag, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+
+	return nil
+}
+
+// rotate the log files, the oldest one is removed
+//
+// MUST hold the lock
+func (w *rotateWriter) rotate() error {
+
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	if w.maxFiles > 0 {
+		os.Remove(fmt.Sprintf("%v.%d", w.path, w.maxFiles))
+		for i := w.maxFiles - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%v.%d", w.path, i), fmt.Sprintf("%v.%d", w.path, i+1))
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	return w.open(os.O_TRUNC)
+}
+
+// Write log, rotate before the max size exceeded
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+
+	w.size += int64(n)
+
+	return n, err
+}
+
+// openRotateWriter of log file, the max size is in bytes, zero means no rotation
+func openRotateWriter(path string, maxSize int64, maxFiles int) (*rotateWriter, error) {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	rt := &rotateWriter{path: path, maxSize: maxSize, maxFiles: maxFiles}
+
+	if err := rt.open(os.O_APPEND); err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readLog(t *testing.T, p string) string {
+	content, err := ioutil.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestRotateWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hanafs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "logs", "hanafs.log")
+
+	w, err := openRotateWriter(p, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := fmt.Fprintf(w, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w.file.Close()
+
+	// each line is 7 bytes, rotated before exceeding 10 bytes, the oldest is removed
+	expected := map[string]string{
+		p:        "line 3\n",
+		p + ".1": "line 2\n",
+		p + ".2": "line 1\n",
+		p + ".3": "",
+	}
+
+	for file, content := range expected {
+		if got := readLog(t, file); got != content {
+			t.Errorf("'%v': got %q, want %q", filepath.Base(file), got, content)
+		}
+	}
+}
+
+func TestRotateWriterAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hanafs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "hanafs.log")
+
+	if err := ioutil.WriteFile(p, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// no rotation
+	w, err := openRotateWriter(p, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Fprint(w, "new\n")
+	w.file.Close()
+
+	if got := readLog(t, p); got != "old\nnew\n" {
+		t.Errorf("got %q", got)
+	}
+
+	if w.size != 8 {
+		t.Errorf("size %v", w.size)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestParseHost(t *testing.T) {
+	cases := []struct {
+		host, scheme, hostPort string
+		valid                  bool
+	}{
+		{"hana.example.com", "", "hana.example.com", true},
+		{"hana.example.com:4300", "", "hana.example.com:4300", true},
+		{"http://hana.example.com:8000", "http", "hana.example.com:8000", true},
+		{"https://hana.example.com", "https", "hana.example.com", true},
+		// path & user info are dropped
+		{"https://user@hana.example.com/sap/hana", "https", "hana.example.com", true},
+		{"ftp://hana.example.com", "", "", false},
+		{"http://%zz", "", "", false},
+	}
+
+	for _, c := range cases {
+		rt, err := parseHost(c.host)
+		if (err == nil) != c.valid {
+			t.Errorf("host '%v', valid %v, got error: %v", c.host, c.valid, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rt.Scheme != c.scheme || rt.Host != c.hostPort || rt.User != nil || len(rt.Path) > 0 {
+			t.Errorf("host '%v', got %#v", c.host, rt)
+		}
+	}
+}
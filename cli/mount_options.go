@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// kinds of mount option value
+const (
+	optionFlag = iota
+	optionString
+	optionNumber
+	optionFloat
+	optionOctal
+)
+
+// mountOptionSpec of a known fuse mount option
+type mountOptionSpec struct {
+	kind int
+	// supported platforms, empty for all
+	platforms []string
+}
+
+// knownMountOptions of fuse, winfsp & osxfuse
+var knownMountOptions = map[string]mountOptionSpec{
+	"allow_other":         {kind: optionFlag},
+	"allow_root":          {kind: optionFlag},
+	"default_permissions": {kind: optionFlag},
+	"ro":                  {kind: optionFlag},
+	"rw":                  {kind: optionFlag},
+	"debug":               {kind: optionFlag},
+	"uid":                 {kind: optionNumber},
+	"gid":                 {kind: optionNumber},
+	"umask":               {kind: optionOctal},
+	"fsname":              {kind: optionString},
+	"subtype":             {kind: optionString, platforms: []string{"linux", "darwin"}},
+	"max_read":            {kind: optionNumber},
+	"attr_timeout":        {kind: optionFloat},
+	"entry_timeout":       {kind: optionFloat},
+	"negative_timeout":    {kind: optionFloat},
+	"auto_unmount":        {kind: optionFlag, platforms: []string{"linux"}},
+	"volname":             {kind: optionString, platforms: []string{"darwin", "windows"}},
+	"noappledouble":       {kind: optionFlag, platforms: []string{"darwin"}},
+	"noapplexattr":        {kind: optionFlag, platforms: []string{"darwin"}},
+	"local":               {kind: optionFlag, platforms: []string{"darwin"}},
+	"auto_cache":          {kind: optionFlag, platforms: []string{"darwin"}},
+}
+
+// check the value of option
+func (s mountOptionSpec) check(name, value string, hasValue bool) error {
+
+	if len(s.platforms) > 0 && !containsString(s.platforms, runtime.GOOS) {
+		return fmt.Errorf("mount option '%v' is not supported on %v", name, runtime.GOOS)
+	}
+
+	switch s.kind {
+	case optionFlag:
+		if hasValue {
+			return fmt.Errorf("mount option '%v' does not accept value", name)
+		}
+	case optionString:
+		if len(value) == 0 {
+			return fmt.Errorf("mount option '%v' requires value", name)
+		}
+	case optionNumber:
+		if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+			return fmt.Errorf("mount option '%v' requires number, got '%v'", name, value)
+		}
+	case optionFloat:
+		if v, err := strconv.ParseFloat(value, 64); err != nil || v < 0 {
+			return fmt.Errorf("mount option '%v' requires non-negative number, got '%v'", name, value)
+		}
+	case optionOctal:
+		if v, err := strconv.ParseUint(value, 8, 32); err != nil || v > 0777 {
+			return fmt.Errorf("mount option '%v' requires octal permission bits, got '%v'", name, value)
+		}
+	}
+
+	return nil
+}
+
+// mountOptions of fuse, parsed from flags
+type mountOptions struct {
+	// names in order, for stable command line
+	names  []string
+	values map[string]string
+}
+
+// add option, the same option with different values is rejected
+//
+// the value of known option is checked, the unknown option is passed through
+func (o *mountOptions) add(option string) error {
+
+	option = strings.TrimSpace(option)
+
+	if len(option) == 0 {
+		return nil
+	}
+
+	parts := strings.SplitN(option, "=", 2)
+	name, value := parts[0], ""
+
+	if len(parts) == 2 {
+		value = parts[1]
+	}
+
+	if spec, known := knownMountOptions[name]; !known {
+		// the fuse implementation may support more options, pass it as is
+		log.Printf("unknown mount option '%v', passed to fuse as is", name)
+	} else if err := spec.check(name, value, len(parts) == 2); err != nil {
+		return err
+	}
+
+	if existed, exist := o.values[name]; exist {
+		if existed != value {
+			return fmt.Errorf("conflicting mount option '%v': '%v' and '%v'", name, existed, value)
+		}
+		return nil
+	}
+
+	o.names = append(o.names, name)
+	o.values[name] = value
+
+	return nil
+}
+
+// has option
+func (o *mountOptions) has(name string) bool {
+	_, exist := o.values[name]
+	return exist
+}
+
+// number value of option, or the default value if not set
+func (o *mountOptions) number(name string, base int, defaultValue int64) int64 {
+	if v, exist := o.values[name]; exist {
+		rt, _ := strconv.ParseUint(v, base, 32)
+		return int64(rt)
+	}
+	return defaultValue
+}
+
+// args of fuse command line
+func (o *mountOptions) args() []string {
+
+	if len(o.names) == 0 {
+		return []string{}
+	}
+
+	options := []string{}
+
+	for _, name := range o.names {
+		if value := o.values[name]; len(value) > 0 {
+			options = append(options, name+"="+value)
+		} else {
+			options = append(options, name)
+		}
+	}
+
+	return []string{"-o", strings.Join(options, ",")}
+}
+
+// validate the combination of options
+func (o *mountOptions) validate() error {
+
+	if o.has("ro") && o.has("rw") {
+		return fmt.Errorf("mount options 'ro' and 'rw' are exclusive")
+	}
+
+	if o.has("allow_other") && o.has("allow_root") {
+		return fmt.Errorf("mount options 'allow_other' and 'allow_root' are exclusive")
+	}
+
+	// fuse rejects the option of non-root user, unless enabled in fuse.conf
+	if (o.has("allow_other") || o.has("allow_root")) && runtime.GOOS == "linux" && os.Getuid() != 0 && !userAllowOther() {
+		return fmt.Errorf("mount option 'allow_other' requires 'user_allow_other' in /etc/fuse.conf")
+	}
+
+	return nil
+}
+
+// userAllowOther check the 'user_allow_other' is enabled in /etc/fuse.conf
+func userAllowOther() bool {
+
+	f, err := os.Open("/etc/fuse.conf")
+
+	if err != nil {
+		return false
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "user_allow_other" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMountOptions from '-o' and the first-class flags
+func parseMountOptions(c *cli.Context) (*mountOptions, error) {
+
+	rt := &mountOptions{values: map[string]string{}}
+
+	raw := []string{}
+
+	for _, value := range c.GlobalStringSlice("option") {
+		raw = append(raw, strings.Split(value, ",")...)
+	}
+
+	if c.GlobalBool("allow-other") {
+		raw = append(raw, "allow_other")
+	}
+
+	if c.GlobalBool("read-only") {
+		raw = append(raw, "ro")
+	}
+
+	if c.GlobalBool("default-permissions") {
+		raw = append(raw, "default_permissions")
+	}
+
+	if c.GlobalBool("noappledouble") {
+		raw = append(raw, "noappledouble")
+	}
+
+	if volname := c.GlobalString("volname"); len(volname) > 0 {
+		raw = append(raw, "volname="+volname)
+	}
+
+	if uid := c.GlobalInt64("uid"); uid >= 0 {
+		raw = append(raw, fmt.Sprintf("uid=%d", uid))
+	}
+
+	if gid := c.GlobalInt64("gid"); gid >= 0 {
+		raw = append(raw, fmt.Sprintf("gid=%d", gid))
+	}
+
+	if umask := c.GlobalString("umask"); len(umask) > 0 {
+		raw = append(raw, "umask="+umask)
+	}
+
+	for _, option := range raw {
+		if err := rt.add(option); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := rt.validate(); err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
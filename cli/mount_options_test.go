@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMountOptionsAdd(t *testing.T) {
+	cases := []struct {
+		option string
+		valid  bool
+	}{
+		{"allow_other", true},
+		{"allow_other=1", false},
+		{"unknown", true},
+		{"unknown=1", true},
+		{"fsname=hana", true},
+		{"fsname", false},
+		{"uid=1000", true},
+		{"uid=-1", false},
+		{"umask=022", true},
+		{"umask=0999", false},
+		{"umask=01000", false},
+		{"attr_timeout=1", true},
+		{"attr_timeout=0.5", true},
+		{"entry_timeout=.25", true},
+		{"negative_timeout=-1", false},
+		{"negative_timeout=abc", false},
+		{" ", true},
+	}
+
+	for _, c := range cases {
+		o := &mountOptions{values: map[string]string{}}
+		if err := o.add(c.option); (err == nil) != c.valid {
+			t.Errorf("option '%v', valid %v, got error: %v", c.option, c.valid, err)
+		}
+	}
+}
+
+func TestMountOptionsConflict(t *testing.T) {
+	o := &mountOptions{values: map[string]string{}}
+
+	for _, option := range []string{"uid=1", "uid=1", "ro"} {
+		if err := o.add(option); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := o.add("uid=2"); err == nil {
+		t.Error("conflicting values should be rejected")
+	}
+
+	if err := o.add("rw"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.validate(); err == nil {
+		t.Error("'ro' and 'rw' should be exclusive")
+	}
+}
+
+func TestMountOptionsArgs(t *testing.T) {
+	o := &mountOptions{values: map[string]string{}}
+
+	if args := o.args(); len(args) != 0 {
+		t.Errorf("no option, got %v", args)
+	}
+
+	for _, option := range []string{"ro", "uid=1000", "umask=022", "attr_timeout=0.5"} {
+		if err := o.add(option); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if args := o.args(); !reflect.DeepEqual(args, []string{"-o", "ro,uid=1000,umask=022,attr_timeout=0.5"}) {
+		t.Errorf("got %v", args)
+	}
+
+	if uid := o.number("uid", 10, -1); uid != 1000 {
+		t.Errorf("uid %v", uid)
+	}
+
+	if umask := o.number("umask", 8, 0); umask != 022 {
+		t.Errorf("umask %o", umask)
+	}
+
+	if gid := o.number("gid", 10, -1); gid != -1 {
+		t.Errorf("gid should be default, got %v", gid)
+	}
+}
+
+func TestMountOptionsPassUnknown(t *testing.T) {
+	o := &mountOptions{values: map[string]string{}}
+
+	for _, option := range []string{"ro", "big_writes", "max_write=131072", "big_writes"} {
+		if err := o.add(option); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := o.add("max_write=4096"); err == nil {
+		t.Error("conflicting values of unknown option should be rejected")
+	}
+
+	if args := o.args(); !reflect.DeepEqual(args, []string{"-o", "ro,big_writes,max_write=131072"}) {
+		t.Errorf("got %v", args)
+	}
+}
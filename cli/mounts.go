@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// mountRecord of a running mount, saved in the runtime directory so that
+// the other hanafs processes could find it by mount point
+type mountRecord struct {
+	PID        int       `json:"pid"`
+	MountPoint string    `json:"mountPoint"`
+	Host       string    `json:"host"`
+	Base       string    `json:"base"`
+	LogFile    string    `json:"logFile,omitempty"`
+	Started    time.Time `json:"started"`
+}
+
+// runtimeDir of the mount records
+func runtimeDir() string {
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); len(dir) > 0 && runtime.GOOS != "windows" {
+		return filepath.Join(dir, "hanafs")
+	}
+
+	// the temp directory of windows is per user
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.TempDir(), "hanafs")
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("hanafs-%d", os.Getuid()))
+}
+
+// absMountPoint normalize the mount point, the drive letter of windows is kept
+func absMountPoint(mountpoint string) string {
+
+	if runtime.GOOS == "windows" && len(mountpoint) == 2 && mountpoint[1] == ':' {
+		return strings.ToUpper(mountpoint)
+	}
+
+	if abs, err := filepath.Abs(mountpoint); err == nil {
+		return abs
+	}
+
+	return mountpoint
+}
+
+// mountID of mount point, the name of runtime files
+func mountID(mountpoint string) string {
+	sum := sha1.Sum([]byte(absMountPoint(mountpoint)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// mountRecordPath of mount point
+func mountRecordPath(mountpoint string) string {
+	return filepath.Join(runtimeDir(), mountID(mountpoint)+".json")
+}
+
+// saveMountRecord of current process
+func saveMountRecord(record *mountRecord) error {
+
+	if err := os.MkdirAll(runtimeDir(), 0700); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(mountRecordPath(record.MountPoint), content, 0600)
+}
+
+// removeMountRecord of mount point
+func removeMountRecord(mountpoint string) {
+	if err := os.Remove(mountRecordPath(mountpoint)); err != nil && !os.IsNotExist(err) {
+		log.Printf("remove mount record of '%v' failed: %v", mountpoint, err)
+	}
+}
+
+// findMountRecord by mount point, nil if not found
+func findMountRecord(mountpoint string) (*mountRecord, error) {
+
+	content, err := ioutil.ReadFile(mountRecordPath(mountpoint))
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rt := &mountRecord{}
+
+	if err := json.Unmarshal(content, rt); err != nil {
+		return nil, fmt.Errorf("invalid mount record of '%v': %v", mountpoint, err)
+	}
+
+	return rt, nil
+}
+
+// listMountRecords of running processes, the stale records will be removed
+func listMountRecords() ([]*mountRecord, error) {
+
+	files, err := ioutil.ReadDir(runtimeDir())
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rt := []*mountRecord{}
+
+	for _, file := range files {
+
+		if filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(runtimeDir(), file.Name()))
+
+		if err != nil {
+			continue
+		}
+
+		record := &mountRecord{}
+
+		if err := json.Unmarshal(content, record); err != nil {
+			log.Printf("invalid mount record '%v': %v", file.Name(), err)
+			continue
+		}
+
+		if !processAlive(record.PID) {
+			removeMountRecord(record.MountPoint)
+			continue
+		}
+
+		rt = append(rt, record)
+
+	}
+
+	return rt, nil
+}
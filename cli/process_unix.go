@@ -0,0 +1,50 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// processAlive check the process is running
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// terminateProcess gracefully, the process will unmount and drain the buffers
+func terminateProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGTERM)
+}
+
+// daemonAttr detach the child from terminal session
+func daemonAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// systemUnmount the stale mount point which owner process is gone
+func systemUnmount(mountpoint string) ([]byte, error) {
+
+	if runtime.GOOS == "darwin" {
+		return exec.Command("umount", mountpoint).CombinedOutput()
+	}
+
+	fusermount := "fusermount"
+
+	if _, err := exec.LookPath(fusermount); err != nil {
+		fusermount = "fusermount3"
+	}
+
+	return exec.Command(fusermount, "-u", mountpoint).CombinedOutput()
+}
@@ -0,0 +1,56 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const processQueryLimitedInformation = 0x1000
+
+const stillActive = 259
+
+// processAlive check the process is running
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// terminateProcess of mount, the console control event could not be sent to
+// another console, so that the process is killed and winfsp removes the mount
+func terminateProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+const (
+	detachedProcess       = 0x00000008
+	createNewProcessGroup = 0x00000200
+)
+
+// daemonAttr detach the child from console
+func daemonAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | createNewProcessGroup,
+		HideWindow:    true,
+	}
+}
+
+// systemUnmount is not required, winfsp removes the mount point after the process exited
+func systemUnmount(mountpoint string) ([]byte, error) {
+	return nil, errors.New("not mounted")
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// stty with the terminal of stdin
+func stty(args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// stdinIsTerminal check the stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// char device maybe not a terminal, e.g. /dev/null
+	return stty("-g") == nil
+}
+
+// readPasswordNoEcho from stdin, the echo is disabled by stty
+func readPasswordNoEcho() (string, error) {
+
+	if err := stty("-echo"); err != nil {
+		return "", err
+	}
+
+	defer stty("echo")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err != nil && len(line) == 0 {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,47 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"syscall"
+)
+
+const enableEchoInput = 0x0004
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// stdinIsTerminal check the stdin is an interactive console
+func stdinIsTerminal() bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(os.Stdin.Fd()), &mode) == nil
+}
+
+// readPasswordNoEcho from console, the echo input mode is disabled
+func readPasswordNoEcho() (string, error) {
+
+	handle := syscall.Handle(os.Stdin.Fd())
+
+	var mode uint32
+
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return "", err
+	}
+
+	if r, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput)); r == 0 {
+		return "", err
+	}
+
+	defer procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err != nil && len(line) == 0 {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,112 @@
+package fs
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultContentCacheSize is the max bytes of cached file content
+const DefaultContentCacheSize = 64 * 1024 * 1024
+
+// DefaultContentCacheEntries is the max number of cached files
+const DefaultContentCacheEntries = 1024
+
+type contentEntry struct {
+	path    string
+	content []byte
+	etag    string
+	// modify time (stat) when the content is cached
+	mtime int64
+}
+
+// ContentCache type
+//
+// in memory LRU cache for file content, the content is validated by etag
+type ContentCache struct {
+	lock       sync.Mutex
+	maxSize    int64
+	maxEntries int
+	size       int64
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+// Get cached content, etag and modify time of path
+func (cc *ContentCache) Get(path string) (content []byte, etag string, mtime int64, exist bool) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	e, exist := cc.entries[path]
+
+	if !exist {
+		return nil, "", 0, false
+	}
+
+	cc.lru.MoveToFront(e)
+
+	entry := e.Value.(*contentEntry)
+
+	return entry.content, entry.etag, entry.mtime, true
+}
+
+// Put content to cache, the least recently used entries will be evicted
+func (cc *ContentCache) Put(path string, content []byte, etag string, mtime int64) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	cc.remove(path)
+
+	// disabled, too large or could not be validated
+	if len(etag) == 0 || cc.maxEntries <= 0 || int64(len(content)) > cc.maxSize {
+		return
+	}
+
+	cc.entries[path] = cc.lru.PushFront(&contentEntry{
+		path:    path,
+		content: content,
+		etag:    etag,
+		mtime:   mtime,
+	})
+
+	cc.size += int64(len(content))
+
+	for cc.size > cc.maxSize || len(cc.entries) > cc.maxEntries {
+		cc.remove(cc.lru.Back().Value.(*contentEntry).path)
+	}
+
+}
+
+// Touch update the modify time of a validated entry
+func (cc *ContentCache) Touch(path string, mtime int64) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	if e, exist := cc.entries[path]; exist {
+		e.Value.(*contentEntry).mtime = mtime
+	}
+}
+
+// Remove cached content of path
+func (cc *ContentCache) Remove(path string) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	cc.remove(path)
+}
+
+func (cc *ContentCache) remove(path string) {
+	if e, exist := cc.entries[path]; exist {
+		cc.lru.Remove(e)
+		delete(cc.entries, path)
+		cc.size -= int64(len(e.Value.(*contentEntry).content))
+	}
+}
+
+// NewContentCache constructor, maxSize in bytes
+func NewContentCache(maxSize int64, maxEntries int) *ContentCache {
+	return &ContentCache{
+		maxSize:    maxSize,
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
@@ -0,0 +1,94 @@
+package fs
+
+import "testing"
+
+func TestContentCacheGetPut(t *testing.T) {
+	cc := NewContentCache(1024, 10)
+
+	cc.Put("/a", []byte("aaa"), "e1", 1)
+
+	content, etag, mtime, exist := cc.Get("/a")
+	if !exist || string(content) != "aaa" || etag != "e1" || mtime != 1 {
+		t.Fatalf("got %q, %q, %v, %v", content, etag, mtime, exist)
+	}
+
+	cc.Touch("/a", 2)
+	if _, _, mtime, _ := cc.Get("/a"); mtime != 2 {
+		t.Errorf("mtime %v after touch", mtime)
+	}
+
+	// replaced
+	cc.Put("/a", []byte("bb"), "e2", 3)
+	if content, etag, _, _ := cc.Get("/a"); string(content) != "bb" || etag != "e2" {
+		t.Errorf("got %q, %q", content, etag)
+	}
+	if cc.size != 2 {
+		t.Errorf("size %v", cc.size)
+	}
+
+	cc.Remove("/a")
+	if _, _, _, exist := cc.Get("/a"); exist || cc.size != 0 {
+		t.Errorf("should be removed, size %v", cc.size)
+	}
+}
+
+func TestContentCacheRejected(t *testing.T) {
+	cc := NewContentCache(4, 10)
+
+	// without etag, could not be validated
+	cc.Put("/a", []byte("a"), "", 1)
+	// too large
+	cc.Put("/b", []byte("bbbbb"), "e", 1)
+
+	for _, p := range []string{"/a", "/b"} {
+		if _, _, _, exist := cc.Get(p); exist {
+			t.Errorf("'%v' should not be cached", p)
+		}
+	}
+
+	disabled := NewContentCache(1024, 0)
+	disabled.Put("/a", []byte("a"), "e", 1)
+	if _, _, _, exist := disabled.Get("/a"); exist {
+		t.Error("cache is disabled")
+	}
+}
+
+func TestContentCacheEvictBySize(t *testing.T) {
+	cc := NewContentCache(6, 10)
+
+	cc.Put("/a", []byte("aa"), "e", 1)
+	cc.Put("/b", []byte("bb"), "e", 1)
+	cc.Put("/c", []byte("cc"), "e", 1)
+
+	// /a is recently used
+	cc.Get("/a")
+
+	cc.Put("/d", []byte("dd"), "e", 1)
+
+	if _, _, _, exist := cc.Get("/b"); exist {
+		t.Error("least recently used should be evicted")
+	}
+	for _, p := range []string{"/a", "/c", "/d"} {
+		if _, _, _, exist := cc.Get(p); !exist {
+			t.Errorf("'%v' should be kept", p)
+		}
+	}
+	if cc.size != 6 {
+		t.Errorf("size %v", cc.size)
+	}
+}
+
+func TestContentCacheEvictByEntries(t *testing.T) {
+	cc := NewContentCache(1024, 2)
+
+	cc.Put("/a", []byte("a"), "e", 1)
+	cc.Put("/b", []byte("b"), "e", 1)
+	cc.Put("/c", []byte("c"), "e", 1)
+
+	if _, _, _, exist := cc.Get("/a"); exist {
+		t.Error("oldest entry should be evicted")
+	}
+	if len(cc.entries) != 2 || cc.lru.Len() != 2 {
+		t.Errorf("entries %v, lru %v", len(cc.entries), cc.lru.Len())
+	}
+}
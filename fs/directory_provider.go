@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"context"
 	"strings"
 
 	"github.com/Soontao/hanafs/hana"
@@ -9,7 +10,7 @@ import (
 )
 
 func trimBasePath(fullpath, basepath string) string {
-	rt := strings.TrimLeft(fullpath, basepath)
+	rt := strings.TrimPrefix(fullpath, basepath)
 	if !strings.HasPrefix(rt, "/") {
 		rt = "/" + rt
 	}
@@ -17,43 +18,49 @@ func trimBasePath(fullpath, basepath string) string {
 	return rt
 }
 
-func deepSearchDirStat(children []hana.Child, basePath string) (rt []*FileSystemStatWrapper) {
+func deepSearchDirStat(children []hana.Child, basePath string, owner *fileOwner) (rt []*FileSystemStatWrapper) {
 
 	basePath = strings.TrimRight(basePath, "/")
 
 	for _, c := range children {
 
 		now := fuse.Now()
-		uid, gid, _ := fuse.Getcontext()
 		path := ""
 
 		s := &FileSystemStat{
 			Nlink: 1,
-			Gid:   gid,
-			Uid:   uid,
 			Atim:  now,
 			Size:  0,
 		}
 
+		sized := false
+
+		owner.fill(s, c.Directory)
+
 		if c.Directory {
-			s.Mode = fuse.S_IFDIR | 0777
 			path = trimBasePath(c.ContentLocation, basePath)
 		} else {
-			s.Mode = fuse.S_IFREG | 0777
 			path = trimBasePath(c.RunLocation, basePath)
 			// file
 			if sBackPack, ok := c.SapBackPack.(string); ok {
 				ts := gjson.Get(sBackPack, "ActivatedAt").Int()
 				s.Mtim = *ToFuseTimeStamp(ts)
-				// refresh size in another location
+			}
+			// otherwise, refresh size in another location
+			if c.Length != nil {
+				s.Size = *c.Length
+				sized = true
 			}
 
 		}
 
-		rt = append(rt, NewFileSystemStatWrapper(path, s))
+		w := NewFileSystemStatWrapper(path, s)
+		w.Sized = sized
+
+		rt = append(rt, w)
 
 		if c.Directory {
-			rt = append(rt, deepSearchDirStat(c.Children, basePath)...)
+			rt = append(rt, deepSearchDirStat(c.Children, basePath, owner)...)
 		}
 
 	}
@@ -62,17 +69,21 @@ func deepSearchDirStat(children []hana.Child, basePath string) (rt []*FileSystem
 }
 
 // CreateDirectoryProvider func
-func CreateDirectoryProvider(client *hana.Client) DirectoryProvider {
+func CreateDirectoryProvider(ctx context.Context, client hana.Backend, owner *fileOwner) DirectoryProvider {
 	return func(path string, depth int64) ([]*FileSystemStatWrapper, error) {
 
 		path = normalizePath(path)
 
-		dir, err := client.ReadDirectory(path, 3)
+		if depth < 1 {
+			depth = 1
+		}
+
+		dir, err := client.ReadDirectoryContext(ctx, path, depth)
 
 		if err != nil {
 			return nil, err
 		}
 
-		return deepSearchDirStat(dir.Children, client.GetBaseDirectory()), nil
+		return deepSearchDirStat(dir.Children, client.GetBaseDirectory(), owner), nil
 	}
 }
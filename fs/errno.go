@@ -0,0 +1,38 @@
+package fs
+
+import (
+	"github.com/Soontao/hanafs/hana"
+	"github.com/billziss-gh/cgofuse/fuse"
+)
+
+// toErrno map the remote error to the (negative) errno of fuse
+func toErrno(err error) int {
+
+	if err == nil {
+		return 0
+	}
+
+	if _, ok := err.(*hana.LockedError); ok {
+		return -fuse.EWOULDBLOCK
+	}
+
+	switch {
+	case hana.IsPreconditionFailed(err):
+		return -fuse.EBUSY
+	case err == hana.ErrOpNotAllowed:
+		return -fuse.EPERM
+	case hana.IsNotFound(err):
+		return -fuse.ENOENT
+	case hana.IsForbidden(err), hana.IsUnauthorized(err):
+		return -fuse.EACCES
+	case hana.IsConflict(err):
+		return -fuse.EEXIST
+	case hana.IsCanceled(err):
+		return -fuse.EINTR
+	case hana.IsTimeout(err):
+		return -fuse.ETIMEDOUT
+	default:
+		return -fuse.EIO
+	}
+
+}
@@ -0,0 +1,235 @@
+package fs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fileHandle is an opened file with a local write back buffer
+//
+// write & truncate only mutate the buffer, the content will be uploaded once in flush
+type fileHandle struct {
+	path    string
+	flags   int
+	lock    sync.Mutex
+	content []byte
+	// etag of loaded content, empty means overwrite without check
+	etag   string
+	loaded bool
+	dirty  bool
+	// the repository lock is acquired by this handle
+	locked bool
+}
+
+func (h *fileHandle) readAt(buff []byte, ofst int64) int {
+	if ofst >= int64(len(h.content)) {
+		return 0
+	}
+	return copy(buff, h.content[ofst:])
+}
+
+func (h *fileHandle) writeAt(buff []byte, ofst int64) int {
+	end := ofst + int64(len(buff))
+	if end > int64(len(h.content)) {
+		h.resize(end)
+	}
+	n := copy(h.content[ofst:end], buff)
+	h.dirty = true
+	return n
+}
+
+// resize buffer, shrink or zero-extend
+func (h *fileHandle) resize(size int64) {
+	if size <= int64(len(h.content)) {
+		h.content = h.content[:size]
+		return
+	}
+	if size <= int64(cap(h.content)) {
+		tail := h.content[len(h.content):size]
+		for i := range tail {
+			tail[i] = 0
+		}
+		h.content = h.content[:size]
+		return
+	}
+	content := make([]byte, size)
+	copy(content, h.content)
+	h.content = content
+}
+
+func (h *fileHandle) truncate(size int64) {
+	h.resize(size)
+	h.dirty = true
+}
+
+// snapshot of current buffer, for upload
+func (h *fileHandle) snapshot() []byte {
+	return append([]byte{}, h.content...)
+}
+
+// handleTable hold all opened file handles
+type handleTable struct {
+	lock    sync.RWMutex
+	next    uint64
+	handles map[uint64]*fileHandle
+}
+
+func newHandleTable() *handleTable {
+	return &handleTable{handles: map[uint64]*fileHandle{}}
+}
+
+// open a new handle, handle id start from 1
+func (t *handleTable) open(path string, flags int) (uint64, *fileHandle) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.next++
+	h := &fileHandle{path: path, flags: flags}
+	t.handles[t.next] = h
+
+	return t.next, h
+}
+
+func (t *handleTable) get(fh uint64) (*fileHandle, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	h, exist := t.handles[fh]
+	return h, exist
+}
+
+// all opened handles
+func (t *handleTable) all() []*fileHandle {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	rt := make([]*fileHandle, 0, len(t.handles))
+
+	for _, h := range t.handles {
+		rt = append(rt, h)
+	}
+
+	return rt
+}
+
+func (t *handleTable) release(fh uint64) (*fileHandle, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	h, exist := t.handles[fh]
+	delete(t.handles, fh)
+	return h, exist
+}
+
+// size of the loaded buffer of path, if any handle have loaded it
+func (t *handleTable) size(path string) (int64, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, h := range t.handles {
+		if h.path != path {
+			continue
+		}
+		h.lock.Lock()
+		loaded, size := h.loaded, int64(len(h.content))
+		h.lock.Unlock()
+		if loaded {
+			return size, true
+		}
+	}
+
+	return 0, false
+}
+
+// isUnder check the path is the base path or its descendant
+func isUnder(path, base string) bool {
+	return path == base || strings.HasPrefix(path, strings.TrimRight(base, "/")+"/")
+}
+
+// movedPath of path after base moved to target
+func movedPath(path, base, target string) string {
+	return target + strings.TrimPrefix(path, base)
+}
+
+// rename the opened handles of path and its descendants, return the renamed handles
+func (t *handleTable) rename(oldpath, newpath string) (rt []*fileHandle) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, h := range t.handles {
+		h.lock.Lock()
+		if isUnder(h.path, oldpath) {
+			h.path = movedPath(h.path, oldpath, newpath)
+			rt = append(rt, h)
+		}
+		h.lock.Unlock()
+	}
+
+	return rt
+}
+
+// resize the loaded buffers of path, after the remote content is truncated
+func (t *handleTable) resize(path string, size int64) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, h := range t.handles {
+		if h.path != path {
+			continue
+		}
+		h.lock.Lock()
+		if h.loaded {
+			h.resize(size)
+		}
+		h.lock.Unlock()
+	}
+}
+
+// setLocked mark the opened handles of path
+func (t *handleTable) setLocked(path string, locked bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, h := range t.handles {
+		if h.path == path {
+			h.locked = locked
+		}
+	}
+}
+
+// lockedPaths of the opened handles under path (inclusive) which hold the repository lock
+func (t *handleTable) lockedPaths(path string) []string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	paths := map[string]bool{}
+
+	for _, h := range t.handles {
+		if h.locked && isUnder(h.path, path) {
+			paths[h.path] = true
+		}
+	}
+
+	rt := []string{}
+
+	for p := range paths {
+		rt = append(rt, p)
+	}
+
+	sort.Strings(rt)
+
+	return rt
+}
+
+// locked check any opened handle of path hold the repository lock
+func (t *handleTable) locked(path string) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, h := range t.handles {
+		if h.path == path && h.locked {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,13 +1,32 @@
 package fs
 
-import "github.com/Soontao/hanafs/hana"
+import (
+	"context"
+	"log"
+
+	"github.com/Soontao/hanafs/hana"
+)
 
 // CreateFileSizeProvider func
-func CreateFileSizeProvider(client *hana.Client) FileSizeProvider {
+//
+// size from HEAD request, download the content only if the server gives no content length
+func CreateFileSizeProvider(ctx context.Context, client hana.Backend) FileSizeProvider {
 	return func(path string) int64 {
-		if content, err := client.ReadFile(path); err == nil {
-			return int64(len(content))
+
+		size, err := client.FileSizeContext(ctx, path)
+
+		if err == hana.ErrSizeUnknown {
+			var content []byte
+			if content, err = client.ReadFileContext(ctx, path); err == nil {
+				size = int64(len(content))
+			}
 		}
-		return 0
+
+		if err != nil {
+			log.Printf("get size of '%v' failed: %v", path, err)
+			return 0
+		}
+
+		return size
 	}
 }
@@ -0,0 +1,67 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Soontao/hanafs/hana"
+)
+
+type sizeBackend struct {
+	hana.Backend
+	sizeErr error
+	reads   int
+}
+
+func (b *sizeBackend) FileSizeContext(ctx context.Context, path string) (int64, error) {
+	if b.sizeErr != nil {
+		return 0, b.sizeErr
+	}
+	return 42, nil
+}
+
+func (b *sizeBackend) ReadFileContext(ctx context.Context, path string) ([]byte, error) {
+	b.reads++
+	return []byte("hello"), nil
+}
+
+func TestFileSizeProvider(t *testing.T) {
+	cases := []struct {
+		name    string
+		sizeErr error
+		size    int64
+		reads   int
+	}{
+		{"head", nil, 42, 0},
+		{"size unknown", hana.ErrSizeUnknown, 5, 1},
+		{"head failed", errors.New("broken"), 0, 0},
+	}
+
+	for _, c := range cases {
+		b := &sizeBackend{sizeErr: c.sizeErr}
+		if size := CreateFileSizeProvider(context.Background(), b)("/a.txt"); size != c.size {
+			t.Errorf("%v: size %v, want %v", c.name, size, c.size)
+		}
+		if b.reads != c.reads {
+			t.Errorf("%v: downloaded %v times, want %v", c.name, b.reads, c.reads)
+		}
+	}
+}
+
+func TestDirectoryListingSize(t *testing.T) {
+	length := int64(7)
+	children := []hana.Child{
+		{Name: "a.txt", RunLocation: "/pkg/a.txt", Length: &length},
+		{Name: "b.txt", RunLocation: "/pkg/b.txt"},
+	}
+
+	rt := deepSearchDirStat(children, "/pkg", &fileOwner{})
+
+	if !rt[0].Sized || rt[0].Stat.Size != 7 {
+		t.Errorf("size of listing should be used, got %v", rt[0].Stat.Size)
+	}
+	if rt[1].Sized {
+		t.Error("size should be unknown")
+	}
+}
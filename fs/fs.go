@@ -1,6 +1,8 @@
 package fs
 
 import (
+	"context"
+	"log"
 	"path/filepath"
 	"time"
 
@@ -15,18 +17,274 @@ const DefaultRemoteCacheSeconds = 15
 // HanaFS type
 type HanaFS struct {
 	fuse.FileSystemBase
-	client    *hana.Client
-	statCache *StatCache
+	client       hana.Backend
+	statCache    *StatCache
+	contentCache *ContentCache
+	handles      *handleTable
+	// last activation errors, path -> error
+	activationErrors *ConcurrentMap
+	// last seen etag of content, path -> etag
+	etags          *ConcurrentMap
+	activateOnSave bool
+	editLocks      bool
+	// ctx of remote calls, cancelled when the file system destroyed
+	ctx    context.Context
+	cancel context.CancelFunc
+	cron   *gron.Cron
 }
 
-func (f *HanaFS) Release(path string, fh uint64) int {
-	f.statCache.UIHaveOpenResource(path)
+// Destroy file system, the background refresh is stopped and the opened handles are drained
+// before the in-flight remote calls are cancelled
+func (f *HanaFS) Destroy() {
+	f.cron.Stop()
+	f.drain()
+	f.cancel()
+	f.statCache.Close()
+}
+
+// drain upload the dirty buffers and release the repository locks of all opened handles,
+// the file system may be unmounted before the handles closed
+func (f *HanaFS) drain() {
+
+	locked := map[string]bool{}
+
+	for _, h := range f.handles.all() {
+		h.lock.Lock()
+		if h.dirty {
+			log.Printf("drain dirty buffer of '%v'", h.path)
+			// the error has been logged
+			f.flushHandle(h)
+		}
+		if h.locked {
+			locked[h.path] = true
+		}
+		h.lock.Unlock()
+	}
+
+	for path := range locked {
+		if err := f.client.UnlockContext(f.ctx, path); err != nil {
+			log.Printf("unlock '%v' failed: %v", path, err)
+		}
+	}
+
+}
+
+// activate object, the error will be logged and kept
+func (f *HanaFS) activate(path string) {
+
+	err := f.client.ActivateContext(f.ctx, path)
+
+	if err != nil {
+		log.Printf("activate '%v' failed: %v", path, err)
+		f.activationErrors.Store(path, err)
+		return
+	}
+
+	f.activationErrors.Delete(path)
+
+	f.statCache.RefreshStat(path)
+
+}
+
+// ActivationError of the last activation of path, nil if activated successful
+func (f *HanaFS) ActivationError(path string) error {
+	if v, exist := f.activationErrors.Load(path); exist {
+		return v.(error)
+	}
+	return nil
+}
+
+// lastETag of path, the etag of last read/write, or the etag of remote metadata
+//
+// used when the old content is not loaded (truncated), so that the remote changes are still detected
+func (f *HanaFS) lastETag(path string) string {
+
+	if v, exist := f.etags.Load(path); exist {
+		return v.(string)
+	}
+
+	stat, err := f.client.StatContext(f.ctx, path)
+
+	if err != nil {
+		// new file or could not be checked, overwrite without check
+		return ""
+	}
+
+	return stat.ETag
+}
+
+// readContent and etag of path, the cached content will be validated with etag
+func (f *HanaFS) readContent(path string) ([]byte, string, error) {
+
+	mtime := int64(0)
+
+	if stat, err := f.statCache.GetStat(path); err == nil {
+		mtime = stat.Mtim.Sec
+	}
+
+	content, etag, cachedMtime, cached := f.contentCache.Get(path)
+
+	// the stat timestamp changed, cached content is outdated
+	if cached && cachedMtime != mtime {
+		f.contentCache.Remove(path)
+		etag = ""
+	}
+
+	remote, remoteETag, err := f.client.ReadFileIfNoneMatchContext(f.ctx, path, etag)
+
+	if err == hana.ErrNotModified {
+		f.etags.Store(path, etag)
+		return content, etag, nil
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	f.etags.Store(path, remoteETag)
+	f.contentCache.Put(path, remote, remoteETag, mtime)
+
+	return remote, remoteETag, nil
+}
+
+// loadHandle content from remote, if not loaded
+//
+// MUST hold the handle lock
+func (f *HanaFS) loadHandle(h *fileHandle) error {
+	if h.loaded {
+		return nil
+	}
+	content, etag, err := f.readContent(h.path)
+	if err != nil {
+		return err
+	}
+	// the content maybe shared with cache
+	h.content = append([]byte{}, content...)
+	h.etag = etag
+	h.loaded = true
+	return nil
+}
+
+// flushHandle upload the buffer if dirty
+//
+// MUST hold the handle lock
+func (f *HanaFS) flushHandle(h *fileHandle) int {
+	if !h.dirty {
+		return 0
+	}
+
+	content := h.snapshot()
+
+	etag, err := f.client.WriteFileContentIfMatchContext(f.ctx, h.path, content, h.etag)
+
+	if hana.IsPreconditionFailed(err) {
+		activatedBy := "unknown"
+		if stat, e := f.client.StatContext(f.ctx, h.path); e == nil {
+			activatedBy = stat.ActivatedBy
+		}
+		log.Printf("flush '%v' failed: remote content has been changed, last activated by '%v'", h.path, activatedBy)
+		return -fuse.EBUSY
+	}
+
+	if err != nil {
+		log.Printf("flush '%v' failed: %v", h.path, err)
+		return toErrno(err)
+	}
+
+	h.dirty = false
+	h.etag = etag
+
+	f.etags.Store(h.path, etag)
+
+	f.statCache.RefreshStatWithSize(h.path, int64(len(content)))
+
+	if stat, err := f.statCache.GetStat(h.path); err == nil {
+		f.contentCache.Put(h.path, content, etag, stat.Mtim.Sec)
+	} else {
+		f.contentCache.Remove(h.path)
+	}
+
+	if f.activateOnSave {
+		f.activate(h.path)
+	}
+
 	return 0
 }
 
+func (f *HanaFS) Flush(path string, fh uint64) int {
+	h, exist := f.handles.get(fh)
+	if !exist {
+		return 0
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return f.flushHandle(h)
+}
+
+func (f *HanaFS) Release(path string, fh uint64) (errc int) {
+	if h, exist := f.handles.release(fh); exist {
+		h.lock.Lock()
+		errc = f.flushHandle(h)
+		h.lock.Unlock()
+		// release the repository lock after the last writer closed
+		if h.locked && !f.handles.locked(h.path) {
+			if err := f.client.UnlockContext(f.ctx, h.path); err != nil {
+				log.Printf("unlock '%v' failed: %v", h.path, err)
+			}
+		}
+	}
+	f.statCache.UIHaveOpenResource(path)
+	return errc
+}
+
+// lockForEditing acquire the repository lock, only the lock held by others will reject the open
+func (f *HanaFS) lockForEditing(path string) (locked bool, errc int) {
+
+	if !f.editLocks {
+		return false, 0
+	}
+
+	err := f.client.LockContext(f.ctx, path)
+
+	if lockedErr, ok := err.(*hana.LockedError); ok {
+		log.Printf("open '%v' for writing failed: locked by '%v'", path, lockedErr.LockedBy)
+		return false, -fuse.EWOULDBLOCK
+	}
+
+	if err != nil {
+		// editing without lock
+		log.Printf("lock '%v' failed: %v", path, err)
+		return false, 0
+	}
+
+	return true, 0
+}
+
 func (f *HanaFS) Open(path string, flags int) (errc int, fh uint64) {
 	f.statCache.UIHaveOpenResource(path)
-	return 0, 0
+
+	locked := false
+
+	if isWriteFlags(flags) {
+		if locked, errc = f.lockForEditing(path); errc != 0 {
+			return errc, 0
+		}
+	}
+
+	fh, h := f.handles.open(path, flags)
+	h.locked = locked
+
+	// O_TRUNC, do not need to load the old content
+	if flags&fuse.O_TRUNC != 0 {
+		h.loaded = true
+		h.dirty = true
+		h.etag = f.lastETag(path)
+		f.statCache.UpdateStatSize(path, 0)
+	}
+
+	return 0, fh
 }
 
 func (f *HanaFS) Opendir(path string) (int, uint64) {
@@ -37,8 +295,9 @@ func (f *HanaFS) Opendir(path string) (int, uint64) {
 func (f *HanaFS) Mkdir(path string, mode uint32) (errc int) {
 	base, name := filepath.Split(path)
 
-	if err := f.client.Create(base, name, true); err != nil {
-		return -fuse.EIO
+	if err := f.client.CreateContext(f.ctx, base, name, true); err != nil {
+		log.Printf("mkdir '%v' failed: %v", path, err)
+		return toErrno(err)
 	}
 
 	f.statCache.FileIsExistNow(path)
@@ -49,18 +308,21 @@ func (f *HanaFS) Mkdir(path string, mode uint32) (errc int) {
 }
 
 func (f *HanaFS) Fsync(path string, datasync bool, fh uint64) int {
-	return 0
+	return f.Flush(path, fh)
 }
 
 func (f *HanaFS) Unlink(path string) (errc int) {
 
 	// remove file
 
-	if err := f.client.Delete(path); err != nil {
-		return -fuse.EIO
+	if err := f.client.DeleteContext(f.ctx, path); err != nil {
+		log.Printf("unlink '%v' failed: %v", path, err)
+		return toErrno(err)
 	}
 
 	f.statCache.RemoveStatCache(path)
+	f.contentCache.Remove(path)
+	f.etags.Delete(path)
 
 	return 0
 }
@@ -69,11 +331,14 @@ func (f *HanaFS) Rmdir(path string) (errc int) {
 
 	// remove directory
 
-	if err := f.client.Delete(path); err != nil {
-		return -fuse.EIO
+	if err := f.client.DeleteContext(f.ctx, path); err != nil {
+		log.Printf("rmdir '%v' failed: %v", path, err)
+		return toErrno(err)
 	}
 
 	f.statCache.RemoveStatCache(path)
+	f.contentCache.Remove(path)
+	f.etags.Delete(path)
 
 	return 0
 }
@@ -82,24 +347,31 @@ func (f *HanaFS) Create(path string, flags int, mode uint32) (int, uint64) {
 
 	base, name := filepath.Split(path)
 
-	if err := f.client.Create(base, name, false); err != nil {
-		return -fuse.EIO, 0
+	if err := f.client.CreateContext(f.ctx, base, name, false); err != nil {
+		log.Printf("create '%v' failed: %v", path, err)
+		return toErrno(err), 0
 	}
 
 	f.statCache.FileIsExistNow(path)
 	f.statCache.RefreshStat(path)
 
-	return 0, 0
+	locked, _ := f.lockForEditing(path)
+
+	// new created file is empty
+	fh, h := f.handles.open(path, flags)
+	h.loaded = true
+	h.locked = locked
+
+	return 0, fh
 
 }
 
 func (f *HanaFS) Utimens(path string, tmsp []fuse.Timespec) (errc int) {
 
 	stat := &fuse.Stat_t{}
-	err := f.Getattr(path, stat, 0)
 
-	if err != 0 {
-		return -fuse.ENOENT
+	if errc = f.Getattr(path, stat, 0); errc != 0 {
+		return errc
 	}
 
 	if tmsp != nil {
@@ -112,44 +384,89 @@ func (f *HanaFS) Utimens(path string, tmsp []fuse.Timespec) (errc int) {
 
 func (f *HanaFS) Write(path string, buff []byte, ofst int64, fh uint64) (n int) {
 
-	data := make([]byte, len(buff))
+	h, exist := f.handles.get(fh)
 
-	copy(data, buff)
+	if !exist {
+		// write without opened handle, upload directly
+		errc, tmp := f.Open(path, fuse.O_WRONLY)
+		if errc != 0 {
+			return errc
+		}
+		defer f.Release(path, tmp)
+		h, _ = f.handles.get(tmp)
+	}
 
-	stat := &fuse.Stat_t{}
-	err := f.Getattr(path, stat, fh)
+	h.lock.Lock()
+	defer h.lock.Unlock()
 
-	if err != 0 {
-		return -fuse.ENOENT
+	if err := f.loadHandle(h); err != nil {
+		log.Printf("load '%v' failed: %v", path, err)
+		return toErrno(err)
 	}
 
-	if ofst != 0 {
-		content, e := f.client.ReadFile(path)
-		if e != nil {
-			// log error here
-			return -fuse.EFAULT
+	n = h.writeAt(buff, ofst)
+
+	f.statCache.UpdateStatSize(path, int64(len(h.content)))
+
+	// return length of write data
+	return n
+}
+
+func (f *HanaFS) Truncate(path string, size int64, fh uint64) (errc int) {
+
+	if h, exist := f.handles.get(fh); exist {
+
+		h.lock.Lock()
+		defer h.lock.Unlock()
+
+		if size > 0 {
+			if err := f.loadHandle(h); err != nil {
+				log.Printf("load '%v' failed: %v", path, err)
+				return toErrno(err)
+			}
+		} else if !h.loaded {
+			h.loaded = true
+			h.etag = f.lastETag(path)
 		}
-		data = append(content[0:ofst], data...)
+
+		h.truncate(size)
+
+		f.statCache.UpdateStatSize(path, size)
+
+		return 0
 	}
 
-	if e := f.client.WriteFileContent(path, data); e != nil {
-		// log error here
-		return -fuse.EFAULT
+	// truncate without opened handle, change the remote content directly
+	content := []byte{}
+	etag := ""
+
+	if size > 0 {
+		c, e, err := f.readContent(path)
+		if err != nil {
+			log.Printf("load '%v' failed: %v", path, err)
+			return toErrno(err)
+		}
+		// size not changed
+		if int64(len(c)) == size {
+			return 0
+		}
+		content = append(content, c...)
+		etag = e
+	} else {
+		etag = f.lastETag(path)
 	}
 
-	f.statCache.RefreshStat(path)
+	h := &fileHandle{path: path, content: content, etag: etag, loaded: true}
 
-	// return length of write data
-	return len(buff)
-}
+	h.truncate(size)
 
-func (f *HanaFS) Truncate(path string, size int64, fh uint64) (errc int) {
-	// mac os/linux change the file size
-	stat, err := f.statCache.GetStat(path)
-	if err != nil {
-		return -fuse.EIO
+	if errc = f.flushHandle(h); errc != 0 {
+		return errc
 	}
-	stat.Size = size
+
+	// other opened handles should see the new size
+	f.handles.resize(path, size)
+
 	return 0
 }
 
@@ -157,8 +474,9 @@ func (f *HanaFS) Mknod(path string, mode uint32, dev uint64) (errc int) {
 
 	base, name := filepath.Split(path)
 
-	if err := f.client.Create(base, name, false); err != nil {
-		return -fuse.EIO
+	if err := f.client.CreateContext(f.ctx, base, name, false); err != nil {
+		log.Printf("mknod '%v' failed: %v", path, err)
+		return toErrno(err)
 	}
 
 	f.statCache.FileIsExistNow(path)
@@ -177,7 +495,7 @@ func (f *HanaFS) Readdir(path string,
 	dir, err := f.statCache.GetDir(path)
 
 	if err != nil {
-		return -fuse.ENOENT
+		return toErrno(err)
 	}
 
 	for _, w := range dir {
@@ -186,9 +504,7 @@ func (f *HanaFS) Readdir(path string,
 		_, sName := filepath.Split(sPath)
 		if len(sPath) > 0 {
 			if oStat.Uid == 0 {
-				uid, gid, _ := fuse.Getcontext()
-				oStat.Uid = uid
-				oStat.Gid = gid
+				oStat.Uid, oStat.Gid = f.statCache.owner.ids()
 			}
 			fill(sName, oStat, 0)
 		}
@@ -198,22 +514,71 @@ func (f *HanaFS) Readdir(path string,
 	return 0
 }
 
+// relock the opened handles of path, the handles are marked as unlocked if the lock could not be acquired
+func (f *HanaFS) relock(path string) {
+	if locked, _ := f.lockForEditing(path); !locked {
+		f.handles.setLocked(path, false)
+	}
+}
+
 func (f *HanaFS) Rename(oldpath string, newpath string) (errc int) {
 	stat, err := f.statCache.GetStat(oldpath)
 
 	if err != nil {
-		return -fuse.ENOENT
+		return toErrno(err)
+	}
+
+	// hana could not move object between packages, it is copied & deleted
+	if _, e := f.statCache.GetStat(newpath); e == nil && !isSameDirectory(oldpath, newpath) {
+		return -fuse.EEXIST
+	}
+
+	// the lock is bound to the path, release the locks of path (and the files under directory)
+	// before the object moved
+	locked := f.handles.lockedPaths(oldpath)
+
+	for _, p := range locked {
+		if err := f.client.UnlockContext(f.ctx, p); err != nil {
+			log.Printf("unlock '%v' failed: %v", p, err)
+		}
 	}
 
-	err = f.client.Rename(oldpath, newpath, isDir(stat.Mode))
+	err = hana.Move(f.ctx, f.client, oldpath, newpath, isDir(stat.Mode))
 
 	if err != nil {
-		// log error here
-		return -fuse.ENOENT
+		log.Printf("rename '%v' to '%v' failed: %v", oldpath, newpath, err)
+		for _, p := range locked {
+			f.relock(p)
+		}
+		return toErrno(err)
 	}
 
-	f.statCache.AddNotExistFileCache(oldpath)
+	for _, h := range f.handles.rename(oldpath, newpath) {
+		h.lock.Lock()
+		// the object maybe copied to another package with new etag
+		if len(h.etag) > 0 {
+			if stat, err := f.client.StatContext(f.ctx, h.path); err == nil {
+				h.etag = stat.ETag
+			}
+		}
+		h.lock.Unlock()
+	}
+
+	for _, p := range locked {
+		f.relock(movedPath(p, oldpath, newpath))
+	}
+
+	f.contentCache.Remove(oldpath)
+	f.etags.Range(func(key, value interface{}) bool {
+		if isUnder(key.(string), oldpath) {
+			f.etags.Delete(key)
+		}
+		return true
+	})
+
+	f.statCache.RemoveStatCacheTree(oldpath)
 	f.statCache.FileIsExistNow(newpath)
+	f.statCache.RefreshStat(newpath)
 
 	return 0
 }
@@ -224,40 +589,46 @@ func (f *HanaFS) Getattr(path string, s *fuse.Stat_t, fh uint64) int {
 	stat, err := f.statCache.GetStat(path)
 
 	if err != nil {
-		return -fuse.ENOENT
+		return toErrno(err)
 	}
 
 	// sometimes, system can not provide correct uid & gid
 	// so assign current user later (here)
 	if stat.Uid == 0 {
-		uid, gid, _ := fuse.Getcontext()
-		stat.Uid = uid
-		stat.Gid = gid
+		stat.Uid, stat.Gid = f.statCache.owner.ids()
 	}
 
 	*s = *stat
 
-	return 0
-
-}
+	// opened file maybe have un-flushed content
+	if size, loaded := f.handles.size(path); loaded {
+		s.Size = size
+	}
 
-// Setxattr for OSX
-func (f *HanaFS) Setxattr(path string, name string, value []byte, flags int) (errc int) {
 	return 0
-}
 
-// Getxattr for OSX
-func (f *HanaFS) Getxattr(path string, name string) (errc int, xatr []byte) {
-	// mac os x attr
-	return -fuse.ENOATTR, nil
 }
 
 // Read content from path
 func (f *HanaFS) Read(path string, buff []byte, ofst int64, fh uint64) (n int) {
-	contents, err := f.client.ReadFile(path)
+
+	if h, exist := f.handles.get(fh); exist {
+
+		h.lock.Lock()
+		defer h.lock.Unlock()
+
+		if err := f.loadHandle(h); err != nil {
+			log.Printf("load '%v' failed: %v", path, err)
+			return toErrno(err)
+		}
+
+		return h.readAt(buff, ofst)
+	}
+
+	contents, _, err := f.readContent(path)
 
 	if err != nil {
-		return -fuse.ENOENT
+		return toErrno(err)
 	}
 
 	endofst := ofst + int64(len(buff))
@@ -291,18 +662,44 @@ var _ fuse.FileSystemChflags = (*HanaFS)(nil)
 var _ fuse.FileSystemSetcrtime = (*HanaFS)(nil)
 var _ fuse.FileSystemSetchgtime = (*HanaFS)(nil)
 
-// NewHanaFS type, initialize logic
-func NewHanaFS(client *hana.Client) *HanaFS {
+// NewHanaFS type, initialize logic, default options will be used if opts is nil
+func NewHanaFS(client hana.Backend, opts *Options) *HanaFS {
+
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fs := &HanaFS{
+		client:       client,
+		statCache:    NewStatCache(ctx, client),
+		contentCache: NewContentCache(opts.ContentCacheSize, opts.ContentCacheEntries),
+		handles:      newHandleTable(),
+
+		activationErrors: &ConcurrentMap{},
+		etags:            &ConcurrentMap{},
+		activateOnSave:   opts.ActivateOnSave,
+		editLocks:        opts.EditLocks,
+
+		ctx:    ctx,
+		cancel: cancel,
+		cron:   gron.New(),
+	}
+
+	if opts.DeepPrefetch {
+		fs.statCache.setMaxDepth(opts.MaxDepth)
+	}
 
-	cron := gron.New()
+	fs.statCache.setWorkers(opts.Workers)
 
-	fs := &HanaFS{client: client, statCache: NewStatCache(client)}
+	fs.statCache.setOwner(opts.Uid, opts.Gid, opts.Umask)
 
 	cronDuration := gron.Every(DefaultRemoteCacheSeconds * time.Second)
 
-	cron.AddFunc(cronDuration, fs.statCache.RefreshCache)
+	fs.cron.AddFunc(cronDuration, fs.statCache.RefreshCache)
 
-	cron.Start()
+	fs.cron.Start()
 
 	return fs
 
@@ -0,0 +1,274 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/Soontao/hanafs/hana"
+	"github.com/Soontao/hanafs/hana/hanatest"
+	"github.com/billziss-gh/cgofuse/fuse"
+)
+
+func newTestFS(t *testing.T, opts *Options) (*HanaFS, *hanatest.Server) {
+	s := hanatest.NewServer()
+	s.WriteFile("/pkg/a.txt", []byte("hello world"))
+	c, err := hana.NewClient(s.ClientURL("/pkg"))
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	return NewHanaFS(c, opts), s
+}
+
+func readAll(t *testing.T, f *HanaFS, path string) string {
+	errc, fh := f.Open(path, fuse.O_RDONLY)
+	if errc != 0 {
+		t.Fatalf("open '%v' failed: %v", path, errc)
+	}
+	defer f.Release(path, fh)
+	buff := make([]byte, 1024)
+	n := f.Read(path, buff, 0, fh)
+	return string(buff[:n])
+}
+
+func TestTruncateSaveDetectsRemoteChange(t *testing.T) {
+	f, s := newTestFS(t, nil)
+	defer s.Close()
+	defer f.Destroy()
+
+	if got := readAll(t, f, "/a.txt"); got != "hello world" {
+		t.Fatalf("read %q", got)
+	}
+
+	s.WriteFile("/pkg/a.txt", []byte("changed remotely"))
+
+	errc, fh := f.Open("/a.txt", fuse.O_WRONLY|fuse.O_TRUNC)
+	if errc != 0 {
+		t.Fatalf("open failed: %v", errc)
+	}
+	f.Write("/a.txt", []byte("mine"), 0, fh)
+
+	if errc := f.Flush("/a.txt", fh); errc != -fuse.EBUSY {
+		t.Errorf("flush should be rejected with EBUSY, got %v", errc)
+	}
+	f.Release("/a.txt", fh)
+
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "changed remotely" {
+		t.Errorf("remote change overwritten: %q", b)
+	}
+}
+
+func TestTruncateToZeroDetectsRemoteChange(t *testing.T) {
+	f, s := newTestFS(t, nil)
+	defer s.Close()
+	defer f.Destroy()
+
+	readAll(t, f, "/a.txt")
+	s.WriteFile("/pkg/a.txt", []byte("changed remotely"))
+
+	if errc := f.Truncate("/a.txt", 0, 0); errc != -fuse.EBUSY {
+		t.Errorf("truncate should be rejected with EBUSY, got %v", errc)
+	}
+}
+
+func TestTruncateSaveWithoutRemoteChange(t *testing.T) {
+	f, s := newTestFS(t, nil)
+	defer s.Close()
+	defer f.Destroy()
+
+	readAll(t, f, "/a.txt")
+
+	errc, fh := f.Open("/a.txt", fuse.O_WRONLY|fuse.O_TRUNC)
+	if errc != 0 {
+		t.Fatalf("open failed: %v", errc)
+	}
+	f.Write("/a.txt", []byte("mine"), 0, fh)
+
+	if errc := f.Release("/a.txt", fh); errc != 0 {
+		t.Fatalf("release failed: %v", errc)
+	}
+
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "mine" {
+		t.Errorf("content not saved: %q", b)
+	}
+}
+
+func TestWriteWithoutHandleLockedByOthers(t *testing.T) {
+	opts := DefaultOptions()
+	opts.EditLocks = true
+	f, s := newTestFS(t, opts)
+	defer s.Close()
+	defer f.Destroy()
+
+	s.LockAs("/pkg/a.txt", "BOB")
+
+	if n := f.Write("/a.txt", []byte("mine"), 0, 0); n != -fuse.EWOULDBLOCK {
+		t.Errorf("write should be rejected with EWOULDBLOCK, got %v", n)
+	}
+
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "hello world" {
+		t.Errorf("locked content changed: %q", b)
+	}
+}
+
+func TestRenameMovesEditLock(t *testing.T) {
+	opts := DefaultOptions()
+	opts.EditLocks = true
+	f, s := newTestFS(t, opts)
+	defer s.Close()
+	defer f.Destroy()
+
+	errc, fh := f.Open("/a.txt", fuse.O_RDWR)
+	if errc != 0 {
+		t.Fatalf("open failed: %v", errc)
+	}
+
+	if errc := f.Rename("/a.txt", "/b.txt"); errc != 0 {
+		t.Fatalf("rename failed: %v", errc)
+	}
+
+	if user := s.LockedBy("/pkg/a.txt"); len(user) > 0 {
+		t.Errorf("old path still locked by '%v'", user)
+	}
+
+	if user := s.LockedBy("/pkg/b.txt"); len(user) == 0 {
+		t.Errorf("new path should be locked")
+	}
+
+	f.Release("/b.txt", fh)
+
+	if user := s.LockedBy("/pkg/b.txt"); len(user) > 0 {
+		t.Errorf("lock should be released on close, locked by '%v'", user)
+	}
+}
+
+func TestRenameDirectoryMovesOpenedFiles(t *testing.T) {
+	for _, target := range []string{"/a/e", "/b/d"} {
+		opts := DefaultOptions()
+		opts.EditLocks = true
+		f, s := newTestFS(t, opts)
+
+		s.WriteFile("/pkg/a/d/x.txt", []byte("old"))
+		s.MkdirAll("/pkg/b")
+
+		errc, fh := f.Open("/a/d/x.txt", fuse.O_RDWR)
+		if errc != 0 {
+			t.Fatalf("open failed: %v", errc)
+		}
+		f.Write("/a/d/x.txt", []byte("new"), 0, fh)
+
+		if errc := f.Rename("/a/d", target); errc != 0 {
+			t.Fatalf("rename to '%v' failed: %v", target, errc)
+		}
+
+		moved := target + "/x.txt"
+
+		if user := s.LockedBy("/pkg/a/d/x.txt"); len(user) > 0 {
+			t.Errorf("%v: old path still locked by '%v'", target, user)
+		}
+		if user := s.LockedBy("/pkg" + moved); len(user) == 0 {
+			t.Errorf("%v: moved file should be locked", target)
+		}
+
+		if errc := f.Flush(moved, fh); errc != 0 {
+			t.Errorf("%v: flush failed: %v", target, errc)
+		}
+		f.Release(moved, fh)
+
+		if b, _ := s.ReadFile("/pkg" + moved); string(b) != "new" {
+			t.Errorf("%v: buffer lost, remote content %q", target, b)
+		}
+		if user := s.LockedBy("/pkg" + moved); len(user) > 0 {
+			t.Errorf("%v: lock should be released on close, locked by '%v'", target, user)
+		}
+
+		f.Destroy()
+		s.Close()
+	}
+}
+
+func TestDestroyDrainsOpenedFiles(t *testing.T) {
+	opts := DefaultOptions()
+	opts.EditLocks = true
+	f, s := newTestFS(t, opts)
+	defer s.Close()
+
+	s.WriteFile("/pkg/b.txt", []byte("untouched"))
+
+	// unmounted before the handles released
+	errc, fh := f.Open("/a.txt", fuse.O_RDWR)
+	if errc != 0 {
+		t.Fatalf("open failed: %v", errc)
+	}
+	f.Write("/a.txt", []byte("HELLO"), 0, fh)
+
+	if errc, _ := f.Open("/b.txt", fuse.O_RDWR); errc != 0 {
+		t.Fatalf("open failed: %v", errc)
+	}
+
+	for _, p := range []string{"/pkg/a.txt", "/pkg/b.txt"} {
+		if user := s.LockedBy(p); len(user) == 0 {
+			t.Fatalf("'%v' should be locked", p)
+		}
+	}
+
+	f.Destroy()
+
+	// the remote requests are sent before the context cancelled
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "HELLO world" {
+		t.Errorf("dirty buffer not uploaded, remote content %q", b)
+	}
+	if b, _ := s.ReadFile("/pkg/b.txt"); string(b) != "untouched" {
+		t.Errorf("clean file should not be uploaded, remote content %q", b)
+	}
+	for _, p := range []string{"/pkg/a.txt", "/pkg/b.txt"} {
+		if user := s.LockedBy(p); len(user) > 0 {
+			t.Errorf("'%v' still locked by '%v'", p, user)
+		}
+	}
+
+	if f.ctx.Err() == nil {
+		t.Error("context should be cancelled after drained")
+	}
+	if !f.statCache.closed {
+		t.Error("stat cache should be closed")
+	}
+}
+
+func TestDrainKeepsGoingAfterFailure(t *testing.T) {
+	opts := DefaultOptions()
+	opts.EditLocks = true
+	f, s := newTestFS(t, opts)
+	defer s.Close()
+	defer f.Destroy()
+
+	s.WriteFile("/pkg/b.txt", []byte("old"))
+
+	errc, fa := f.Open("/a.txt", fuse.O_RDWR)
+	if errc != 0 {
+		t.Fatalf("open failed: %v", errc)
+	}
+	f.Write("/a.txt", []byte("HELLO"), 0, fa)
+
+	errc, fb := f.Open("/b.txt", fuse.O_RDWR)
+	if errc != 0 {
+		t.Fatalf("open failed: %v", errc)
+	}
+	f.Write("/b.txt", []byte("new"), 0, fb)
+
+	// changed by others, the upload is rejected
+	s.WriteFile("/pkg/a.txt", []byte("changed remotely"))
+
+	f.drain()
+
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "changed remotely" {
+		t.Errorf("remote change overwritten: %q", b)
+	}
+	if b, _ := s.ReadFile("/pkg/b.txt"); string(b) != "new" {
+		t.Errorf("dirty buffer not uploaded, remote content %q", b)
+	}
+	for _, p := range []string{"/pkg/a.txt", "/pkg/b.txt"} {
+		if user := s.LockedBy(p); len(user) > 0 {
+			t.Errorf("'%v' still locked by '%v'", p, user)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package fs
+
+// DefaultMaxDepth of directory prefetch
+const DefaultMaxDepth = 3
+
+// Options of hana file system
+type Options struct {
+	// ContentCacheSize is the max bytes of cached file content, 0 to disable
+	ContentCacheSize int64
+	// ContentCacheEntries is the max number of cached files, 0 to disable
+	ContentCacheEntries int
+	// ActivateOnSave activate the object after content uploaded
+	ActivateOnSave bool
+	// EditLocks acquire the repository lock when file opened for writing, disabled by default
+	EditLocks bool
+	// DeepPrefetch load the sub directories (until MaxDepth) when directory opened
+	DeepPrefetch bool
+	// MaxDepth of directory prefetch, only used when DeepPrefetch enabled
+	MaxDepth int64
+	// Workers is the max concurrent remote requests of prefetch
+	Workers int
+	// Uid & Gid of files, negative to use the uid & gid of the calling process
+	Uid int64
+	Gid int64
+	// Umask of the permission of files
+	Umask uint32
+}
+
+// DefaultOptions for hana file system
+func DefaultOptions() *Options {
+	return &Options{
+		ContentCacheSize:    DefaultContentCacheSize,
+		ContentCacheEntries: DefaultContentCacheEntries,
+		MaxDepth:            DefaultMaxDepth,
+		Workers:             DefaultWorkers,
+		Uid:                 -1,
+		Gid:                 -1,
+	}
+}
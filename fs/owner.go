@@ -0,0 +1,39 @@
+package fs
+
+import (
+	"github.com/billziss-gh/cgofuse/fuse"
+)
+
+// DefaultPermission of files & directories, before umask applied
+const DefaultPermission = 0777
+
+// fileOwner fill the owner & permission of stats
+type fileOwner struct {
+	// negative to use the uid/gid of the calling process
+	uid   int64
+	gid   int64
+	umask uint32
+}
+
+// ids of owner, the uid & gid of the calling process are used if not specified
+func (o *fileOwner) ids() (uid, gid uint32) {
+	uid, gid, _ = fuse.Getcontext()
+	if o.uid >= 0 {
+		uid = uint32(o.uid)
+	}
+	if o.gid >= 0 {
+		gid = uint32(o.gid)
+	}
+	return
+}
+
+// fill uid, gid & mode of stat
+func (o *fileOwner) fill(s *FileSystemStat, dir bool) {
+	s.Uid, s.Gid = o.ids()
+	perm := uint32(DefaultPermission) &^ o.umask
+	if dir {
+		s.Mode = fuse.S_IFDIR | perm
+	} else {
+		s.Mode = fuse.S_IFREG | perm
+	}
+}
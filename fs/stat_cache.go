@@ -1,11 +1,12 @@
 package fs
 
 import (
+	"context"
 	"log"
-	"path/filepath"
 	"strings"
 	"sync"
 
+	"github.com/Jeffail/tunny"
 	"github.com/Soontao/hanafs/hana"
 	"github.com/billziss-gh/cgofuse/fuse"
 )
@@ -14,14 +15,79 @@ import (
 //
 // in memory stat cache
 type StatCache struct {
-	cache            *ConcurrentMap
-	openResource     *ConcurrentMap
+	cache        *ConcurrentMap
+	openResource *ConcurrentMap
+	// owner & permission of the provided stats
+	owner            *fileOwner
 	statProvider     StatProvider
 	dirProvider      DirectoryProvider
 	fileSizeProvider FileSizeProvider
 	maxDepthLock     sync.RWMutex
 	refreshLock      sync.Mutex
 	maxDepth         int64
+	// directories which children have been loaded
+	loadedDirs *ConcurrentMap
+	// workers for the parallel remote prefetch
+	pool     *tunny.Pool
+	poolLock sync.RWMutex
+	closed   bool
+	// hana metadata of path & delivery unit of package, dropped once the stat updated
+	metas         *ConcurrentMap
+	deliveryUnits *ConcurrentMap
+	client        hana.Backend
+	ctx           context.Context
+}
+
+// DefaultWorkers is the max concurrent remote requests of prefetch
+const DefaultWorkers = 8
+
+func (sc *StatCache) setWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	sc.pool.SetSize(n)
+}
+
+// parallel run the tasks in worker pool and wait all of them finished
+//
+// the tasks MUST NOT submit other tasks to the pool, otherwise dead lock
+func (sc *StatCache) parallel(tasks []func()) {
+
+	sc.poolLock.RLock()
+	defer sc.poolLock.RUnlock()
+
+	// the pool has been closed, run in current goroutine
+	if sc.closed {
+		for _, task := range tasks {
+			task()
+		}
+		return
+	}
+
+	wg := sync.WaitGroup{}
+
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task func()) {
+			defer wg.Done()
+			// block until a worker is ready
+			sc.pool.Process(task)
+		}(task)
+	}
+
+	wg.Wait()
+
+}
+
+// Close the worker pool, wait the running tasks finished
+func (sc *StatCache) Close() {
+	sc.poolLock.Lock()
+	defer sc.poolLock.Unlock()
+
+	if !sc.closed {
+		sc.closed = true
+		sc.pool.Close()
+	}
 }
 
 func (sc *StatCache) setMaxDepth(depth int64) {
@@ -30,6 +96,11 @@ func (sc *StatCache) setMaxDepth(depth int64) {
 	sc.maxDepth = depth
 }
 
+// setOwner of stats, MUST be called before the stats provided
+func (sc *StatCache) setOwner(uid, gid int64, umask uint32) {
+	*sc.owner = fileOwner{uid: uid, gid: gid, umask: umask}
+}
+
 // GetMaxDepth for current
 func (sc *StatCache) GetMaxDepth() int64 {
 	sc.maxDepthLock.RLock()
@@ -47,28 +118,9 @@ func (sc *StatCache) UIHaveOpenResource(path string) {
 		sc.openResource.Store(path, true)
 		sc.RefreshStat(path)
 
-		// if opened resource is dir, preload sub dir of the dir
+		// if opened resource is dir, load the children (and prefetch deep if configured)
 		if stat, err := sc.GetStat(path); err == nil && isDir(stat.Mode) {
-
-			// refresh current dir stat
-			sc.RefreshDir(path, false)
-
-			if d, e := sc.GetDir(path); e == nil && d != nil {
-
-				for _, w := range d {
-					sPath := w.Path
-					oStat := w.Stat
-					if oStat != nil {
-						if isDir(oStat.Mode) {
-							sc.RefreshDir(sPath, false)
-						} else {
-							sc.RefreshStat(sPath)
-						}
-					}
-				}
-
-			}
-
+			sc.RefreshDir(path, true)
 		}
 	}
 
@@ -76,19 +128,20 @@ func (sc *StatCache) UIHaveOpenResource(path string) {
 
 // IsOpenedDirectoryFile data
 func (sc *StatCache) IsOpenedDirectoryFile(path string) (rt bool) {
-
-	path = normalizePath(path)
-
-	dir, _ := filepath.Split(path)
-
-	if len(dir) > 1 {
-		dir = strings.TrimRight(dir, "/")
-	}
-
-	_, rt = sc.openResource.Load(dir)
-
+	_, rt = sc.openResource.Load(parentDir(path))
 	return
+}
 
+// visitedDirectories which have been opened by user
+func (sc *StatCache) visitedDirectories() (rt []string) {
+	sc.openResource.Range(func(key interface{}, value interface{}) bool {
+		p := key.(string)
+		if v, exist := sc.cache.Load(p); exist && isDir(v.(*fuse.Stat_t).Mode) {
+			rt = append(rt, p)
+		}
+		return true
+	})
+	return rt
 }
 
 func (sc *StatCache) cacheRangeAll(f func(path string, stat *fuse.Stat_t)) {
@@ -148,13 +201,7 @@ func (sc *StatCache) GetDirStats(path string) (rt []*FileSystemStatWrapper) {
 		if aPath == path {
 			return
 		}
-		base, _ := filepath.Split(aPath)
-
-		if len(base) > 1 {
-			base = strings.TrimRight(base, "/")
-		}
-
-		if base == path {
+		if parentDir(aPath) == path {
 			rt = append(rt, NewFileSystemStatWrapper(aPath, oStat))
 		}
 
@@ -172,7 +219,7 @@ func (sc *StatCache) GetStat(path string) (*fuse.Stat_t, error) {
 
 	// if have pre load, but can not found in cache
 	// it means not exist
-	if sc.IsOpenedDirectoryFile(path) {
+	if sc.IsLoadedDirectoryFile(path) {
 		return nil, hana.ErrFileNotFound
 	}
 
@@ -195,39 +242,41 @@ func (sc *StatCache) GetStatDirect(path string) (*fuse.Stat_t, error) {
 		return nil, err
 	}
 
-	if !isDir(v.Mode) {
-
-		if c, exist := sc.cache.Load(path); exist {
-
-			currentStat := c.(*fuse.Stat_t)
-
-			// if changed, retrive the new size
-			if sc.IsOpenedDirectoryFile(path) && (currentStat.Mtim.Sec != v.Mtim.Sec || currentStat.Size == 0) {
-				v.Size = sc.fileSizeProvider(path)
-			} else {
-				return currentStat, nil
-			}
-
-		} else {
-			v.Size = sc.fileSizeProvider(path)
-		}
-
-	}
-
-	if err != nil {
-		return nil, err
+	// metadata does not provide the size, retrive it
+	if !isDir(v.Mode) && v.Size < 0 {
+		v.Size = sc.fileSizeProvider(path)
 	}
 
 	return v, nil
 }
 
-// RefreshCache stats
+// RefreshCache stats, only the visited directories will be refreshed
 func (sc *StatCache) RefreshCache() {
 	// ensure only one goroutine run refresh job
 	sc.refreshLock.Lock()
 	defer sc.refreshLock.Unlock()
 
-	sc.RefreshDir("/", true)
+	dirs := sc.visitedDirectories()
+	lists := make([][]*FileSystemStatWrapper, len(dirs))
+	errs := make([]error, len(dirs))
+	tasks := []func(){}
+
+	for i, dir := range dirs {
+		i, dir := i, dir
+		tasks = append(tasks, func() {
+			lists[i], errs[i] = sc.dirProvider(dir, 1)
+		})
+	}
+
+	sc.parallel(tasks)
+
+	for i, dir := range dirs {
+		if errs[i] != nil {
+			log.Printf("refresh dir '%v' failed: %v", dir, errs[i])
+			continue
+		}
+		sc.updateDir(dir, 1, lists[i])
+	}
 
 	return
 }
@@ -237,11 +286,19 @@ func (sc *StatCache) PreCacheDirectory(path string, v []*FileSystemStatWrapper)
 
 	path = normalizePath(path)
 
+	// file size lookups are executed in parallel
+	sizeTasks := []func(){}
+
 	for _, w := range v {
 
 		aPath := w.Path
 		oStat := w.Stat
 
+		// the listing provides the size
+		if w.Sized {
+			continue
+		}
+
 		if c, exist := sc.cache.Load(aPath); exist {
 
 			currentStat := c.(*FileSystemStat)
@@ -249,7 +306,7 @@ func (sc *StatCache) PreCacheDirectory(path string, v []*FileSystemStatWrapper)
 			if !isDir(currentStat.Mode) {
 				if currentStat.Mtim.Sec != oStat.Mtim.Sec || (sc.IsOpenedDirectoryFile(aPath) && currentStat.Size == 0) {
 					// if updated, update file size info.
-					oStat.Size = sc.fileSizeProvider(aPath)
+					sizeTasks = append(sizeTasks, func() { oStat.Size = sc.fileSizeProvider(aPath) })
 				} else {
 					// if not updated, use old size
 					oStat.Size = currentStat.Size
@@ -260,121 +317,146 @@ func (sc *StatCache) PreCacheDirectory(path string, v []*FileSystemStatWrapper)
 
 			if sc.IsOpenedDirectoryFile(aPath) {
 				// first time added
-				oStat.Size = sc.fileSizeProvider(aPath)
+				sizeTasks = append(sizeTasks, func() { oStat.Size = sc.fileSizeProvider(aPath) })
 			}
 
 		}
 
-		sc.PreCacheStat(aPath, oStat)
+	}
+
+	sc.parallel(sizeTasks)
 
+	for _, w := range v {
+		sc.PreCacheStat(w.Path, w.Stat)
 	}
 
 }
 
-// GetDir inner content directly, if not exist, will retrive and cache it
+// GetDir inner content directly, if not loaded, will retrive and cache it
 func (sc *StatCache) GetDir(path string) ([]*FileSystemStatWrapper, error) {
 
-	if _, exist := sc.cache.Load(path); exist {
+	path = normalizePath(path)
+
+	if sc.IsLoadedDirectory(path) {
 		return sc.GetDirStats(path), nil
 	}
 
-	v, err := sc.GetDirDirect(path, false)
+	depth := sc.dirDepth(true)
+
+	v, err := sc.dirProvider(path, depth)
 
 	if err != nil {
 		return nil, err
 	}
 
 	sc.PreCacheDirectory(path, v)
+	sc.markLoaded(path, depth, v)
 
-	return v, nil
+	return sc.GetDirStats(path), nil
 }
 
 // GetDirDirect func, without cache & pre cache
 func (sc *StatCache) GetDirDirect(path string, deepRefresh bool) ([]*FileSystemStatWrapper, error) {
+	return sc.dirProvider(path, sc.dirDepth(deepRefresh))
+}
 
-	depth := int64(1)
-
-	// if deep refresh
-	if deepRefresh {
-		depth = sc.GetMaxDepth() + 1
-	}
-
-	// preload stat deep
-	v, err := sc.dirProvider(path, depth)
-
-	if err != nil {
-		return nil, err
+// dirDepth of directory loading, one level unless deep and max depth is configured
+func (sc *StatCache) dirDepth(deep bool) int64 {
+	if deep && sc.GetMaxDepth() > 1 {
+		return sc.GetMaxDepth()
 	}
-
-	return v, nil
-
+	return 1
 }
 
 // CleanNotExistedFiles list
 //
-// MUST provide the full files list from remote
-func (sc *StatCache) CleanNotExistedFiles(dirPath string, fullList []*FileSystemStatWrapper) {
+// MUST provide the full files list (with depth) of directory from remote
+func (sc *StatCache) CleanNotExistedFiles(dirPath string, depth int64, fullList []*FileSystemStatWrapper) {
 
-	remoteNotExistedNow := []string{}
+	dirPath = normalizePath(dirPath)
 
-	sc.cacheRangeAll(func(path string, stat *fuse.Stat_t) {
+	remoteNotExistedNow := []string{}
 
-		iDepth := int64(len(strings.Split(path, "/")))
+	stillExist := map[string]bool{}
 
-		if iDepth > sc.GetMaxDepth() {
-			return
-		}
+	for _, aFSStat := range fullList {
+		stillExist[aFSStat.Path] = true
+	}
 
-		if !strings.HasPrefix(path, dirPath) {
-			return
-		}
+	sc.cacheRangeAll(func(path string, stat *fuse.Stat_t) {
 
-		if path == dirPath {
+		if iDepth := relativeDepth(dirPath, path); iDepth < 1 || iDepth > depth {
 			return
 		}
 
-		stillExist := false
-
-		for _, aFSStat := range fullList {
-			if aFSStat.Path == path {
-				stillExist = true
-				break
-			}
-		}
-
-		if !stillExist {
+		if !stillExist[path] {
 			remoteNotExistedNow = append(remoteNotExistedNow, path)
 		}
 
 	})
 
 	for _, removedPath := range remoteNotExistedNow {
-		sc.RemoveStatCache(removedPath)
+		sc.RemoveStatCacheTree(removedPath)
+		sc.loadedDirs.Delete(removedPath)
 	}
 }
 
 // RefreshDir and item stats
+//
+// only one level will be loaded, unless deep refresh and max depth is configured
 func (sc *StatCache) RefreshDir(path string, deepRefresh bool) {
 
-	dir, err := sc.GetDirDirect(path, deepRefresh)
+	path = normalizePath(path)
+
+	depth := sc.dirDepth(deepRefresh)
+
+	dir, err := sc.dirProvider(path, depth)
 
 	if err == nil {
-		if deepRefresh {
-			sc.CleanNotExistedFiles(path, dir)
-		}
-		sc.PreCacheDirectory(path, dir)
+		sc.updateDir(path, depth, dir)
 	} else {
 		log.Printf("refresh dir '%v' failed: %v", path, err)
 	}
 
 }
 
+// updateDir cache with the full list (with depth) of directory
+func (sc *StatCache) updateDir(path string, depth int64, dir []*FileSystemStatWrapper) {
+	sc.CleanNotExistedFiles(path, depth, dir)
+	sc.PreCacheDirectory(path, dir)
+	sc.markLoaded(path, depth, dir)
+}
+
+// markLoaded the directories which children have been loaded
+func (sc *StatCache) markLoaded(path string, depth int64, v []*FileSystemStatWrapper) {
+
+	sc.loadedDirs.Store(path, true)
+
+	for _, w := range v {
+		if isDir(w.Stat.Mode) && relativeDepth(path, w.Path) < depth {
+			sc.loadedDirs.Store(w.Path, true)
+		}
+	}
+
+}
+
+// IsLoadedDirectory check the children of directory have been loaded
+func (sc *StatCache) IsLoadedDirectory(path string) (rt bool) {
+	_, rt = sc.loadedDirs.Load(normalizePath(path))
+	return
+}
+
+// IsLoadedDirectoryFile check the parent directory children have been loaded
+func (sc *StatCache) IsLoadedDirectoryFile(path string) bool {
+	return sc.IsLoadedDirectory(parentDir(path))
+}
+
 // RefreshStat value
 func (sc *StatCache) RefreshStat(path string) {
 	if v, err := sc.GetStatDirect(path); err == nil {
 		sc.PreCacheStat(path, v)
 	} else {
-		if err == hana.ErrFileNotFound {
+		if hana.IsNotFound(err) {
 			sc.RemoveStatCache(path)
 		} else {
 			log.Println(err)
@@ -382,6 +464,23 @@ func (sc *StatCache) RefreshStat(path string) {
 	}
 }
 
+// RefreshStatWithSize value, the size is known by caller so that not need to retrive it
+func (sc *StatCache) RefreshStatWithSize(path string, size int64) {
+	if v, err := sc.statProvider(path); err == nil {
+		v.Size = size
+		sc.PreCacheStat(path, v)
+	} else {
+		log.Println(err)
+	}
+}
+
+// UpdateStatSize of cached stat, for local changed content
+func (sc *StatCache) UpdateStatSize(path string, size int64) {
+	if v, exist := sc.cache.Load(path); exist {
+		v.(*fuse.Stat_t).Size = size
+	}
+}
+
 // PreCacheStat value
 func (sc *StatCache) PreCacheStat(path string, v *fuse.Stat_t) {
 	path = strings.ReplaceAll(path, "\\", "/")
@@ -392,23 +491,102 @@ func (sc *StatCache) PreCacheStat(path string, v *fuse.Stat_t) {
 
 func (sc *StatCache) setCache(path string, v *fuse.Stat_t) {
 	sc.cache.Store(path, v)
+	sc.removeMeta(path)
 }
 
 // RemoveStatCache value
 func (sc *StatCache) RemoveStatCache(path string) {
 
 	sc.cache.Delete(path)
+	sc.removeMeta(path)
+
+}
+
+func (sc *StatCache) removeMeta(path string) {
+	sc.metas.Delete(path)
+	sc.deliveryUnits.Delete(path)
+}
+
+// GetMeta of hana object, cached until the stat of path updated
+func (sc *StatCache) GetMeta(path string) (*hana.PathStat, error) {
+
+	path = normalizePath(path)
+
+	if v, exist := sc.metas.Load(path); exist {
+		return v.(*hana.PathStat), nil
+	}
+
+	v, err := sc.client.StatContext(sc.ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sc.metas.Store(path, v)
+
+	return v, nil
+}
+
+// GetDeliveryUnit of package (directory), cached until the stat of directory updated
+func (sc *StatCache) GetDeliveryUnit(dir string) string {
+
+	dir = normalizePath(dir)
+
+	if v, exist := sc.deliveryUnits.Load(dir); exist {
+		return v.(string)
+	}
+
+	detail, err := sc.client.ReadDirectoryContext(sc.ctx, dir, 1)
+
+	if err != nil {
+		log.Printf("get delivery unit of '%v' failed: %v", dir, err)
+		return ""
+	}
+
+	rt := ""
+
+	if detail.SapBackPack.DeliveryUnit != nil {
+		rt = *detail.SapBackPack.DeliveryUnit
+	}
+
+	sc.deliveryUnits.Store(dir, rt)
+
+	return rt
+}
+
+// RemoveStatCacheTree value of path and all children
+func (sc *StatCache) RemoveStatCacheTree(path string) {
+
+	path = normalizePath(path)
+
+	sc.cacheRangeAll(func(aPath string, oStat *fuse.Stat_t) {
+		if aPath == path || strings.HasPrefix(aPath, path+"/") {
+			sc.RemoveStatCache(aPath)
+			sc.loadedDirs.Delete(aPath)
+		}
+	})
 
 }
 
-// NewStatCache constructor
-func NewStatCache(client *hana.Client) *StatCache {
+// NewStatCache constructor, the remote calls will be aborted once the ctx is done
+func NewStatCache(ctx context.Context, client hana.Backend) *StatCache {
+	owner := &fileOwner{uid: -1, gid: -1}
 	return &StatCache{
 		cache:            &ConcurrentMap{},
-		statProvider:     CreateStatProvider(client),
-		dirProvider:      CreateDirectoryProvider(client),
-		fileSizeProvider: CreateFileSizeProvider(client),
+		owner:            owner,
+		statProvider:     CreateStatProvider(ctx, client, owner),
+		dirProvider:      CreateDirectoryProvider(ctx, client, owner),
+		fileSizeProvider: CreateFileSizeProvider(ctx, client),
 		openResource:     &ConcurrentMap{},
+		loadedDirs:       &ConcurrentMap{},
 		maxDepth:         1,
+		metas:            &ConcurrentMap{},
+		deliveryUnits:    &ConcurrentMap{},
+		client:           client,
+		ctx:              ctx,
+		pool: tunny.NewFunc(DefaultWorkers, func(payload interface{}) interface{} {
+			payload.(func())()
+			return nil
+		}),
 	}
 }
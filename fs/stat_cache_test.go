@@ -0,0 +1,133 @@
+package fs
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/billziss-gh/cgofuse/fuse"
+)
+
+func dirStat(path string) *FileSystemStatWrapper {
+	return NewFileSystemStatWrapper(path, &FileSystemStat{Mode: fuse.S_IFDIR | 0755})
+}
+
+func fileStat(path string) *FileSystemStatWrapper {
+	return NewFileSystemStatWrapper(path, &FileSystemStat{Mode: fuse.S_IFREG | 0644})
+}
+
+// cachedPaths of stat cache, sorted
+func cachedPaths(sc *StatCache) (rt []string) {
+	sc.cacheRangeAll(func(path string, stat *fuse.Stat_t) {
+		rt = append(rt, path)
+	})
+	sort.Strings(rt)
+	return rt
+}
+
+func TestStatCacheClose(t *testing.T) {
+	sc := NewStatCache(context.Background(), nil)
+
+	sc.Close()
+	sc.Close()
+
+	if size := sc.pool.GetSize(); size != 0 {
+		t.Errorf("workers should be stopped, got %v", size)
+	}
+
+	// the late tasks still run after closed
+	done := 0
+	sc.parallel([]func(){func() { done++ }, func() { done++ }})
+
+	if done != 2 {
+		t.Errorf("tasks should run after closed, got %v", done)
+	}
+}
+
+func TestStatCacheDirDepth(t *testing.T) {
+	sc := NewStatCache(context.Background(), nil)
+	defer sc.Close()
+
+	if d := sc.dirDepth(true); d != 1 {
+		t.Errorf("default depth %v, want 1", d)
+	}
+
+	sc.setMaxDepth(3)
+
+	if d := sc.dirDepth(true); d != 3 {
+		t.Errorf("deep depth %v, want 3", d)
+	}
+	if d := sc.dirDepth(false); d != 1 {
+		t.Errorf("shallow depth %v, want 1", d)
+	}
+}
+
+func TestStatCacheMarkLoaded(t *testing.T) {
+	sc := NewStatCache(context.Background(), nil)
+	defer sc.Close()
+
+	sc.markLoaded("/a", 2, []*FileSystemStatWrapper{
+		dirStat("/a/b"),
+		fileStat("/a/f"),
+		dirStat("/a/b/c"),
+	})
+
+	for path, loaded := range map[string]bool{
+		"/a":   true,
+		"/a/b": true,
+		"/a/f": false,
+		// the children of deepest level are not listed
+		"/a/b/c": false,
+		"/x":     false,
+	} {
+		if rt := sc.IsLoadedDirectory(path); rt != loaded {
+			t.Errorf("'%v' loaded %v, want %v", path, rt, loaded)
+		}
+	}
+
+	// the missing file of loaded directory is known as not existed without remote request
+	if !sc.IsLoadedDirectoryFile("/a/b/missing") {
+		t.Error("parent directory should be loaded")
+	}
+}
+
+func TestStatCacheCleanNotExistedFiles(t *testing.T) {
+	sc := NewStatCache(context.Background(), nil)
+	defer sc.Close()
+
+	for _, w := range []*FileSystemStatWrapper{
+		dirStat("/a"),
+		fileStat("/a/f"),
+		fileStat("/a/removed"),
+		dirStat("/a/b"),
+		fileStat("/a/b/g"),
+		dirStat("/a/b/c"),
+		fileStat("/a/b/c/deep"),
+		dirStat("/a/gone"),
+		fileStat("/a/gone/h"),
+		fileStat("/ab"),
+	} {
+		sc.PreCacheStat(w.Path, w.Stat)
+	}
+
+	sc.markLoaded("/a/gone", 1, nil)
+
+	// listed with depth 2, the deeper stats are kept
+	sc.CleanNotExistedFiles("/a", 2, []*FileSystemStatWrapper{
+		fileStat("/a/f"),
+		dirStat("/a/b"),
+		fileStat("/a/b/g"),
+		dirStat("/a/b/c"),
+	})
+
+	want := []string{"/a", "/a/b", "/a/b/c", "/a/b/c/deep", "/a/b/g", "/a/f", "/ab"}
+
+	if got := cachedPaths(sc); !reflect.DeepEqual(got, want) {
+		t.Errorf("cached %v, want %v", got, want)
+	}
+
+	if sc.IsLoadedDirectory("/a/gone") {
+		t.Error("removed directory should not be loaded")
+	}
+}
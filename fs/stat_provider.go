@@ -1,17 +1,19 @@
 package fs
 
 import (
+	"context"
+
 	"github.com/Soontao/hanafs/hana"
 	"github.com/billziss-gh/cgofuse/fuse"
 )
 
 // CreateStatProvider func
-func CreateStatProvider(client *hana.Client) StatProvider {
+func CreateStatProvider(ctx context.Context, client hana.Backend, owner *fileOwner) StatProvider {
 	return func(path string) (*fuse.Stat_t, error) {
 
 		path = normalizePath(path)
 
-		hanaStat, err := client.Stat(path)
+		hanaStat, err := client.StatContext(ctx, path)
 
 		if err != nil {
 			return nil, err
@@ -19,21 +21,18 @@ func CreateStatProvider(client *hana.Client) StatProvider {
 
 		now := fuse.Now()
 
-		uid, gid, _ := fuse.Getcontext()
-
 		s := &fuse.Stat_t{
 			Nlink: 1,
-			Gid:   gid,
-			Uid:   uid,
 			Atim:  now,
 			Mtim:  *ToFuseTimeStamp(hanaStat.TimeStamp),
 			Size:  0,
 		}
 
-		if hanaStat.Directory {
-			s.Mode = fuse.S_IFDIR | 0777
-		} else {
-			s.Mode = fuse.S_IFREG | 0777 // Regular File.
+		owner.fill(s, hanaStat.Directory)
+
+		if !hanaStat.Directory {
+			// negative means unknown
+			s.Size = hanaStat.Size
 		}
 
 		return s, nil
@@ -20,6 +20,8 @@ type FileSystemStatWrapper struct {
 	Path string
 	// stat infomation
 	Stat *FileSystemStat
+	// Sized is true if the file size is provided by the directory listing
+	Sized bool
 }
 
 // NewFileSystemStatWrapper constructor
@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/billziss-gh/cgofuse/fuse"
@@ -27,3 +28,43 @@ func normalizePath(p string) string {
 func isDir(mode uint32) bool {
 	return (mode & fuse.S_IFMT) == fuse.S_IFDIR
 }
+
+// isWriteFlags check the open flags request write access
+func isWriteFlags(flags int) bool {
+	return flags&fuse.O_ACCMODE != fuse.O_RDONLY || flags&fuse.O_TRUNC != 0
+}
+
+// isSameDirectory check two path have the same parent directory
+func isSameDirectory(oldpath, newpath string) bool {
+	oldDir, _ := filepath.Split(oldpath)
+	newDir, _ := filepath.Split(newpath)
+	return oldDir == newDir
+}
+
+// parentDir of path, without the tail slash
+func parentDir(p string) string {
+
+	dir, _ := filepath.Split(normalizePath(p))
+
+	if len(dir) > 1 {
+		dir = strings.TrimRight(dir, "/")
+	}
+
+	return dir
+}
+
+// relativeDepth of path to base directory, 0 if equal, -1 if not under base
+func relativeDepth(base, p string) int64 {
+
+	base = strings.TrimRight(base, "/")
+
+	if p == base || (len(base) == 0 && p == "/") {
+		return 0
+	}
+
+	if !strings.HasPrefix(p, base+"/") {
+		return -1
+	}
+
+	return int64(len(strings.Split(strings.TrimPrefix(p, base+"/"), "/")))
+}
@@ -0,0 +1,28 @@
+package fs
+
+import "testing"
+
+func TestRelativeDepth(t *testing.T) {
+	cases := []struct {
+		base, path string
+		depth      int64
+	}{
+		{"/", "/", 0},
+		{"", "/", 0},
+		{"/", "/a", 1},
+		{"/", "/a/b", 2},
+		{"/a", "/a", 0},
+		{"/a/", "/a", 0},
+		{"/a", "/a/b", 1},
+		{"/a", "/a/b/c", 2},
+		{"/a", "/ab", -1},
+		{"/a", "/b/a", -1},
+		{"/a/b", "/a", -1},
+	}
+
+	for _, c := range cases {
+		if depth := relativeDepth(c.base, c.path); depth != c.depth {
+			t.Errorf("relativeDepth(%q, %q) = %v, want %v", c.base, c.path, depth, c.depth)
+		}
+	}
+}
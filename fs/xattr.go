@@ -0,0 +1,126 @@
+package fs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/billziss-gh/cgofuse/fuse"
+)
+
+// XattrPrefix of the read-only hana extended attributes
+const XattrPrefix = "user.hana."
+
+func isHanaXattr(name string) bool {
+	return strings.HasPrefix(name, XattrPrefix)
+}
+
+// hanaXattrs of path, name -> value, the empty values are omitted
+func (f *HanaFS) hanaXattrs(path string) (map[string]string, error) {
+
+	path = normalizePath(path)
+
+	stat, err := f.statCache.GetMeta(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]string{}
+
+	if stat.Directory {
+		attrs["deliveryUnit"] = f.statCache.GetDeliveryUnit(path)
+	} else {
+		// file belongs to the delivery unit of its package
+		attrs["deliveryUnit"] = f.statCache.GetDeliveryUnit(parentDir(path))
+		attrs["activated"] = strconv.FormatBool(stat.Activated)
+		attrs["activatedBy"] = stat.ActivatedBy
+		attrs["version"] = strconv.FormatInt(stat.Version, 10)
+		attrs["objectStatus"] = stat.ObjectStatus
+		attrs["etag"] = stat.ETag
+		attrs["contentType"] = stat.ContentType
+		if stat.TimeStamp > 0 {
+			attrs["activatedAt"] = time.Unix(0, stat.TimeStamp*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+		}
+	}
+
+	if err := f.ActivationError(path); err != nil {
+		attrs["activationError"] = err.Error()
+	}
+
+	rt := map[string]string{}
+
+	for name, value := range attrs {
+		if len(value) > 0 {
+			rt[XattrPrefix+name] = value
+		}
+	}
+
+	return rt, nil
+}
+
+// Setxattr for OSX, hana attributes are read-only
+func (f *HanaFS) Setxattr(path string, name string, value []byte, flags int) (errc int) {
+	if isHanaXattr(name) {
+		return -fuse.EPERM
+	}
+	return 0
+}
+
+// Removexattr, hana attributes are read-only
+func (f *HanaFS) Removexattr(path string, name string) (errc int) {
+	if isHanaXattr(name) {
+		return -fuse.EPERM
+	}
+	return -fuse.ENOATTR
+}
+
+// Getxattr for hana attributes
+func (f *HanaFS) Getxattr(path string, name string) (errc int, xatr []byte) {
+
+	// mac os x attr
+	if !isHanaXattr(name) {
+		return -fuse.ENOATTR, nil
+	}
+
+	attrs, err := f.hanaXattrs(path)
+
+	if err != nil {
+		return toErrno(err), nil
+	}
+
+	value, exist := attrs[name]
+
+	if !exist {
+		return -fuse.ENOATTR, nil
+	}
+
+	return 0, []byte(value)
+}
+
+// Listxattr of hana attributes
+func (f *HanaFS) Listxattr(path string, fill func(name string) bool) (errc int) {
+
+	attrs, err := f.hanaXattrs(path)
+
+	if err != nil {
+		return toErrno(err)
+	}
+
+	names := []string{}
+
+	for name := range attrs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !fill(name) {
+			return -fuse.ERANGE
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,72 @@
+package fs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Soontao/hanafs/hana"
+	"github.com/Soontao/hanafs/hana/hanatest"
+)
+
+type countingBackend struct {
+	hana.Backend
+	stats int32
+	dirs  int32
+}
+
+func (b *countingBackend) StatContext(ctx context.Context, path string) (*hana.PathStat, error) {
+	atomic.AddInt32(&b.stats, 1)
+	return b.Backend.StatContext(ctx, path)
+}
+
+func (b *countingBackend) ReadDirectoryContext(ctx context.Context, path string, depth int64) (*hana.DirectoryDetail, error) {
+	atomic.AddInt32(&b.dirs, 1)
+	return b.Backend.ReadDirectoryContext(ctx, path, depth)
+}
+
+func TestXattrsServedFromCache(t *testing.T) {
+	s := hanatest.NewServer()
+	defer s.Close()
+
+	s.WriteFile("/pkg/a.txt", []byte("a"))
+	s.WriteFile("/pkg/b.txt", []byte("b"))
+	s.SetDeliveryUnit("/pkg", "du")
+
+	c, err := hana.NewClient(s.ClientURL("/pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &countingBackend{Backend: c}
+	f := NewHanaFS(b, nil)
+	defer f.Destroy()
+
+	for i := 0; i < 3; i++ {
+		for _, p := range []string{"/a.txt", "/b.txt"} {
+			f.Listxattr(p, func(name string) bool {
+				f.Getxattr(p, name)
+				return true
+			})
+		}
+	}
+
+	if errc, v := f.Getxattr("/a.txt", XattrPrefix+"deliveryUnit"); errc != 0 || string(v) != "du" {
+		t.Errorf("delivery unit %q, errc %v", v, errc)
+	}
+
+	if b.stats != 2 {
+		t.Errorf("stat requested %v times, want once per file", b.stats)
+	}
+	if b.dirs != 1 {
+		t.Errorf("package requested %v times, want once per directory", b.dirs)
+	}
+
+	// updated stat drops the cached metadata
+	f.statCache.RefreshStat("/a.txt")
+	before := b.stats
+	f.Getxattr("/a.txt", XattrPrefix+"etag")
+	if b.stats != before+1 {
+		t.Errorf("metadata should be fetched again after stat updated, %v -> %v", before, b.stats)
+	}
+}
@@ -0,0 +1,270 @@
+package hana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imroc/req"
+)
+
+// Authenticator is the authentication strategy of client
+type Authenticator interface {
+	// Authorize the request, set the credential to header
+	//
+	// r is the requester of client, could be used to retrive token
+	Authorize(ctx context.Context, r *req.Req, header req.Header) error
+	// Refresh the credential after it is rejected by server (401),
+	// return false if it could not be refreshed
+	Refresh(ctx context.Context, r *req.Req) bool
+}
+
+// BasicAuth authenticator, with user & password
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
+// Authorize with basic authorization header, omitted if user is empty (client certificate authentication)
+func (a *BasicAuth) Authorize(ctx context.Context, r *req.Req, header req.Header) error {
+	if len(a.User) > 0 {
+		header[keyAuthorization] = basicAuth(a.User, a.Password)
+	}
+	return nil
+}
+
+// Refresh is not supported
+func (a *BasicAuth) Refresh(ctx context.Context, r *req.Req) bool {
+	return false
+}
+
+// TokenSource provide the bearer token
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken source
+func StaticToken(token string) TokenSource {
+	return func(ctx context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// FileToken source, the token file will be read again when refresh
+func FileToken(path string) TokenSource {
+	return func(ctx context.Context) (string, error) {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+}
+
+// CommandToken source, the stdout of command is the token, the command will be executed again when refresh
+func CommandToken(command string) TokenSource {
+	return func(ctx context.Context) (string, error) {
+		token, err := RunCommand(ctx, command)
+		return strings.TrimSpace(token), err
+	}
+}
+
+// RunCommand by shell, return the first line of stdout
+//
+// the stdin & stderr are inherited, the command maybe interactive (e.g. unlock the password store)
+func RunCommand(ctx context.Context, command string) (string, error) {
+
+	var cmd *exec.Cmd
+
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return "", fmt.Errorf("command '%v' failed: %v", command, err)
+	}
+
+	return strings.TrimRight(strings.SplitN(string(out), "\n", 2)[0], "\r"), nil
+}
+
+// BearerAuth authenticator, the token will be cached until rejected
+type BearerAuth struct {
+	source TokenSource
+	// static token could not be refreshed
+	static bool
+	lock   sync.Mutex
+	token  string
+}
+
+// Authorize with bearer token
+func (a *BearerAuth) Authorize(ctx context.Context, r *req.Req, header req.Header) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.token) == 0 {
+		token, err := a.source(ctx)
+		if err != nil {
+			return err
+		}
+		if len(token) == 0 {
+			return errors.New("empty bearer token")
+		}
+		a.token = token
+	}
+
+	header[keyAuthorization] = "Bearer " + a.token
+
+	return nil
+}
+
+// Refresh token from source
+func (a *BearerAuth) Refresh(ctx context.Context, r *req.Req) bool {
+	if a.static {
+		return false
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.token = ""
+	return true
+}
+
+// NewBearerAuth with static token
+func NewBearerAuth(token string) *BearerAuth {
+	return &BearerAuth{source: StaticToken(token), static: true}
+}
+
+// NewBearerAuthWithSource with dynamic token, e.g. FileToken & CommandToken
+func NewBearerAuthWithSource(source TokenSource) *BearerAuth {
+	return &BearerAuth{source: source}
+}
+
+// oauthTokenExpirySkew the token will be refreshed before it expired
+const oauthTokenExpirySkew = 30 * time.Second
+
+// OAuthAuth authenticator, retrive the access token from token url with
+// client credentials grant, or password grant if the user is provided
+type OAuthAuth struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	User         string
+	Password     string
+
+	lock    sync.Mutex
+	token   string
+	expired time.Time
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// fetchToken from token url
+//
+// MUST hold the lock
+func (a *OAuthAuth) fetchToken(ctx context.Context, r *req.Req) error {
+
+	param := req.Param{"grant_type": "client_credentials"}
+
+	if len(a.User) > 0 {
+		param = req.Param{
+			"grant_type": "password",
+			"username":   a.User,
+			"password":   a.Password,
+		}
+	}
+
+	header := req.Header{
+		keyAuthorization: basicAuth(a.ClientID, a.ClientSecret),
+		"Accept":         "application/json",
+	}
+
+	res, err := r.Post(a.TokenURL, param, header, ctx)
+
+	if err != nil {
+		return newTransportError("POST", a.TokenURL, err)
+	}
+
+	if res.Response().StatusCode != http.StatusOK {
+		return newStatusError("POST", a.TokenURL, res)
+	}
+
+	token := &oauthTokenResponse{}
+
+	if err := res.ToJSON(token); err != nil {
+		return err
+	}
+
+	if len(token.AccessToken) == 0 {
+		return errors.New("no access token in oauth response")
+	}
+
+	a.token = token.AccessToken
+	a.expired = time.Time{}
+
+	if token.ExpiresIn > 0 {
+		a.expired = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - oauthTokenExpirySkew)
+	}
+
+	return nil
+}
+
+// Authorize with access token, the token will be retrived if not fetched or expired
+func (a *OAuthAuth) Authorize(ctx context.Context, r *req.Req, header req.Header) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.token) == 0 || (!a.expired.IsZero() && time.Now().After(a.expired)) {
+		if err := a.fetchToken(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	header[keyAuthorization] = "Bearer " + a.token
+
+	return nil
+}
+
+// Refresh access token
+func (a *OAuthAuth) Refresh(ctx context.Context, r *req.Req) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.token = ""
+	return true
+}
+
+// CookieAuth authenticator, reuse the session cookies (MYSAPSSO2, xsSecureId ...) of a logged in browser
+type CookieAuth struct {
+	// Cookies in header format, e.g. 'MYSAPSSO2=...; xsSecureId=...'
+	Cookies string
+}
+
+// Authorize with session cookies
+func (a *CookieAuth) Authorize(ctx context.Context, r *req.Req, header req.Header) error {
+	header["Cookie"] = a.Cookies
+	return nil
+}
+
+// Refresh is not supported, the session should be logged in again
+func (a *CookieAuth) Refresh(ctx context.Context, r *req.Req) bool {
+	return false
+}
+
+var _ Authenticator = (*BasicAuth)(nil)
+var _ Authenticator = (*BearerAuth)(nil)
+var _ Authenticator = (*OAuthAuth)(nil)
+var _ Authenticator = (*CookieAuth)(nil)
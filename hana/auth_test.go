@@ -0,0 +1,289 @@
+package hana_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Soontao/hanafs/hana"
+	"github.com/Soontao/hanafs/hana/hanatest"
+)
+
+// newAuthServer only accept the configured credentials
+func newAuthServer() *hanatest.Server {
+	s := hanatest.NewServer()
+	s.SetCredential("BOB", "secret")
+	s.WriteFile("/pkg/a.txt", []byte("hello"))
+	return s
+}
+
+func newAuthClient(t *testing.T, s *hanatest.Server, auth hana.Authenticator) *hana.Client {
+	c, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), &hana.ClientOptions{Auth: auth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// lockedAs check the request is authenticated as user
+func lockedAs(t *testing.T, c *hana.Client, s *hanatest.Server, user string) {
+	if err := c.Lock("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if holder := s.LockedBy("/pkg/a.txt"); holder != user {
+		t.Errorf("locked by %q, want %q", holder, user)
+	}
+	if err := c.Unlock("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaticBearerAuth(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+
+	s.SetBearerToken("t1", "ALICE")
+
+	auth := hana.NewBearerAuth("t1")
+	c := newAuthClient(t, s, auth)
+
+	lockedAs(t, c, s, "ALICE")
+
+	if auth.Refresh(context.Background(), nil) {
+		t.Error("static token could not be refreshed")
+	}
+
+	// revoked token is not retried
+	s.SetBearerToken("t1", "")
+
+	if _, err := c.ReadFile("/a.txt"); !hana.IsUnauthorized(err) {
+		t.Errorf("unauthorized expected, got %v", err)
+	}
+}
+
+func TestFileBearerAuth(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "hanafs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+
+	if err := ioutil.WriteFile(path, []byte("t1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetBearerToken("t1", "ALICE")
+
+	c := newAuthClient(t, s, hana.NewBearerAuthWithSource(hana.FileToken(path)))
+
+	lockedAs(t, c, s, "ALICE")
+
+	// the token is rotated, the rejected request is retried with the new token
+	if err := ioutil.WriteFile(path, []byte("t2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.SetBearerToken("t1", "")
+	s.SetBearerToken("t2", "ALICE")
+
+	if content, err := c.ReadFile("/a.txt"); err != nil || string(content) != "hello" {
+		t.Errorf("read %q, %v", content, err)
+	}
+
+	// the refreshed token is still rejected
+	s.SetBearerToken("t2", "")
+
+	if _, err := c.ReadFile("/a.txt"); !hana.IsUnauthorized(err) {
+		t.Errorf("unauthorized expected, got %v", err)
+	}
+}
+
+func TestCommandBearerAuth(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell command")
+	}
+
+	s := newAuthServer()
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "hanafs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+
+	if err := ioutil.WriteFile(path, []byte("t1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetBearerToken("t1", "ALICE")
+
+	c := newAuthClient(t, s, hana.NewBearerAuthWithSource(hana.CommandToken("cat '"+path+"'")))
+
+	lockedAs(t, c, s, "ALICE")
+
+	// the command is executed again after rejected
+	if err := ioutil.WriteFile(path, []byte("t2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.SetBearerToken("t1", "")
+	s.SetBearerToken("t2", "ALICE")
+
+	if content, err := c.ReadFile("/a.txt"); err != nil || string(content) != "hello" {
+		t.Errorf("read %q, %v", content, err)
+	}
+
+	// the failed command is reported
+	if _, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), &hana.ClientOptions{
+		Auth: hana.NewBearerAuthWithSource(hana.CommandToken("exit 1")),
+	}); err == nil {
+		t.Error("command error expected")
+	}
+}
+
+// tokenServer of oauth, the issued tokens are accepted by the server as user
+type tokenServer struct {
+	*httptest.Server
+	expiresIn int64
+	issued    int32
+}
+
+func newTokenServer(s *hanatest.Server, user string) *tokenServer {
+	ts := &tokenServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "client-secret" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "unsupported grant type", http.StatusBadRequest)
+			return
+		}
+		token := fmt.Sprintf("o%d", atomic.AddInt32(&ts.issued, 1))
+		s.SetBearerToken(token, user)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": token,
+			"expires_in":   atomic.LoadInt64(&ts.expiresIn),
+		})
+	}))
+	return ts
+}
+
+func TestOAuthAuth(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+
+	ts := newTokenServer(s, "ALICE")
+	defer ts.Close()
+
+	atomic.StoreInt64(&ts.expiresIn, 3600)
+
+	c := newAuthClient(t, s, &hana.OAuthAuth{
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "client-secret",
+	})
+
+	lockedAs(t, c, s, "ALICE")
+
+	// the token is cached until expired
+	if n := atomic.LoadInt32(&ts.issued); n != 1 {
+		t.Errorf("issued %v tokens, want 1", n)
+	}
+
+	// the rejected token is refreshed
+	s.SetBearerToken("o1", "")
+
+	if content, err := c.ReadFile("/a.txt"); err != nil || string(content) != "hello" {
+		t.Errorf("read %q, %v", content, err)
+	}
+	if n := atomic.LoadInt32(&ts.issued); n != 2 {
+		t.Errorf("issued %v tokens, want 2", n)
+	}
+
+	// invalid client credentials
+	if _, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), &hana.ClientOptions{
+		Auth: &hana.OAuthAuth{TokenURL: ts.URL, ClientID: "client"},
+	}); !hana.IsUnauthorized(err) {
+		t.Errorf("unauthorized expected, got %v", err)
+	}
+}
+
+func TestOAuthAuthExpired(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+
+	ts := newTokenServer(s, "ALICE")
+	defer ts.Close()
+
+	// shorter than the expiry skew, the token is fetched for each request
+	atomic.StoreInt64(&ts.expiresIn, 1)
+
+	c := newAuthClient(t, s, &hana.OAuthAuth{
+		TokenURL:     ts.URL,
+		ClientID:     "client",
+		ClientSecret: "client-secret",
+	})
+
+	issued := atomic.LoadInt32(&ts.issued)
+
+	if _, err := c.Stat("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&ts.issued); n <= issued {
+		t.Errorf("expired token should be fetched again, issued %v tokens", n)
+	}
+}
+
+func TestCookieAuth(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+
+	s.SetSessionCookie("session", "ALICE")
+
+	auth := &hana.CookieAuth{Cookies: "MYSAPSSO2=session"}
+	c := newAuthClient(t, s, auth)
+
+	lockedAs(t, c, s, "ALICE")
+
+	if auth.Refresh(context.Background(), nil) {
+		t.Error("session cookie could not be refreshed")
+	}
+
+	// session expired
+	s.SetSessionCookie("session", "")
+
+	if _, err := c.ReadFile("/a.txt"); !hana.IsUnauthorized(err) {
+		t.Errorf("unauthorized expected, got %v", err)
+	}
+}
+
+func TestBasicAuthRejected(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+
+	c := newAuthClient(t, s, &hana.BasicAuth{User: "BOB", Password: "secret"})
+
+	lockedAs(t, c, s, "BOB")
+
+	if _, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), &hana.ClientOptions{
+		Auth: &hana.BasicAuth{User: "BOB", Password: "wrong"},
+	}); err == nil {
+		t.Error("wrong password should be rejected")
+	}
+}
@@ -0,0 +1,43 @@
+package hana
+
+import "context"
+
+// Backend is the abstraction of hana xs repository operations
+//
+// the file system only depends on this interface, so that the transport
+// could be swapped or decorated (cache, log, retry ...)
+//
+// the remote call will be aborted once the ctx is done
+type Backend interface {
+	// GetBaseDirectory of the repository
+	GetBaseDirectory() string
+	// StatContext file or directory metadata
+	StatContext(ctx context.Context, path string) (*PathStat, error)
+	// ReadDirectoryContext children information with depth
+	ReadDirectoryContext(ctx context.Context, path string, depth int64) (*DirectoryDetail, error)
+	// FileSizeContext without downloading content
+	FileSizeContext(ctx context.Context, path string) (int64, error)
+	// ReadFileContext content
+	ReadFileContext(ctx context.Context, path string) ([]byte, error)
+	// ReadFileIfNoneMatchContext read content if the etag changed
+	ReadFileIfNoneMatchContext(ctx context.Context, path, etag string) ([]byte, string, error)
+	// WriteFileContentContext to an existed file
+	WriteFileContentContext(ctx context.Context, path string, content []byte) error
+	// WriteFileContentIfMatchContext write content if the etag not changed
+	WriteFileContentIfMatchContext(ctx context.Context, path string, content []byte, etag string) (string, error)
+	// CreateContext file or directory under base
+	CreateContext(ctx context.Context, base, name string, dir bool) error
+	// DeleteContext file or directory
+	DeleteContext(ctx context.Context, path string) error
+	// RenameContext file or directory
+	RenameContext(ctx context.Context, old, new string, dir bool) error
+	// ActivateContext objects
+	ActivateContext(ctx context.Context, paths ...string) error
+	// LockContext object for editing
+	LockContext(ctx context.Context, path string) error
+	// UnlockContext object
+	UnlockContext(ctx context.Context, path string) error
+}
+
+// Client is the default Backend implementation
+var _ Backend = (*Client)(nil)
@@ -1,10 +1,12 @@
 package hana
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
@@ -26,6 +28,23 @@ const keyContentType = "Content-Type"
 
 const valueRequired = "required"
 
+const keyETag = "ETag"
+
+const keyIfNoneMatch = "If-None-Match"
+
+const keyIfMatch = "If-Match"
+
+const keySapBackPack = "SapBackPack"
+
+// DefaultRetries of idempotent request
+const DefaultRetries = 3
+
+// DefaultRetryBackoff is the wait duration before the first retry, doubled for each retry
+const DefaultRetryBackoff = 200 * time.Millisecond
+
+// DefaultTimeout of a single request
+const DefaultTimeout = 60 * time.Second
+
 // Client type
 type Client struct {
 	uri           *url.URL
@@ -34,6 +53,34 @@ type Client struct {
 	sslVerify     bool
 	baseDirectory string
 	tokenLock     sync.RWMutex
+	retries       int
+	retryBackoff  time.Duration
+	timeout       time.Duration
+	auth          Authenticator
+}
+
+// SetTimeout of a single request, 0 means no timeout
+func (c *Client) SetTimeout(d time.Duration) {
+	c.timeout = d
+	c.req.SetTimeout(d)
+}
+
+// Endpoint of the repository
+func (c *Client) Endpoint() string {
+	return c.formatURI(c.formatDtFilePath("/"))
+}
+
+// ProxyURL of the endpoint, nil if not proxied
+func (c *Client) ProxyURL() (*url.URL, error) {
+	trans, ok := c.req.Client().Transport.(*http.Transport)
+	if !ok || trans.Proxy == nil {
+		return nil, nil
+	}
+	r, err := http.NewRequest("GET", c.Endpoint(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return trans.Proxy(r)
 }
 
 // GetBaseDirectory path
@@ -56,12 +103,20 @@ func (c *Client) setToken(tokenValue string) {
 }
 
 func (c *Client) formatURI(path string) string {
+	scheme := c.uri.Scheme
+	// default to https
+	if len(scheme) == 0 {
+		scheme = "https"
+	}
 	port := c.uri.Port()
-	// default to 443 port
+	// default to the well known port of scheme
 	if len(port) == 0 {
 		port = "443"
+		if scheme == "http" {
+			port = "80"
+		}
 	}
-	return fmt.Sprintf("https://%s:%s%s", c.uri.Host, port, path)
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(c.uri.Hostname(), port), path)
 }
 
 func isCSRFTokenError(response *http.Response) bool {
@@ -71,6 +126,11 @@ func isCSRFTokenError(response *http.Response) bool {
 
 // Rename file or move file
 func (c *Client) Rename(old, new string, dir bool) (err error) {
+	return c.RenameContext(context.Background(), old, new, dir)
+}
+
+// RenameContext file or move file, with context
+func (c *Client) RenameContext(ctx context.Context, old, new string, dir bool) (err error) {
 	// only support rename
 	// if users want to move from directory to another, maybe a delete & create walkaround required.
 
@@ -96,7 +156,15 @@ func (c *Client) Rename(old, new string, dir bool) (err error) {
 		"X-Create-Options": "move,no-overwrite",
 	}
 
-	res, err := c.request("POST", c.formatDtFilePath(oldPath), req.BodyJSON(&payload), header)
+	res, err := c.request(ctx, "POST", c.formatDtFilePath(oldPath), req.BodyJSON(&payload), header)
+
+	// no-overwrite, the target is existed
+	if IsPreconditionFailed(err) {
+		rt := err.(*RequestError)
+		rt.URL = c.formatURI(c.formatDtFilePath(new))
+		rt.Kind = KindConflict
+		return rt
+	}
 
 	if err != nil {
 		return err
@@ -109,67 +177,185 @@ func (c *Client) Rename(old, new string, dir bool) (err error) {
 	return
 }
 
-func (c *Client) request(method, path string, infos ...interface{}) (*req.Resp, error) {
+// isIdempotent method could be retried safely
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// isConditional request with If-Match header, it is not retried
+//
+// the write may have been applied before the connection reset, so the retry
+// will be rejected by the changed etag and the caller will see a false conflict
+func isConditional(infos []interface{}) bool {
+	for _, info := range infos {
+		if header, ok := info.(req.Header); ok && len(header[keyIfMatch]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry the failed request, server errors & broken connections are transient
+//
+// the request timed out by client is not retried, it has taken the whole timeout
+func shouldRetry(err error) bool {
+	rErr, ok := err.(*RequestError)
+	if !ok {
+		return false
+	}
+	if rErr.Err != nil {
+		return rErr.Kind != KindTimeout && isConnectionReset(rErr.Err)
+	}
+	return rErr.Kind == KindServerError || rErr.Kind == KindTimeout
+}
+
+// backoff duration before the retry, exponential with jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.retryBackoff << uint(attempt)
+	if wait <= 0 {
+		return 0
+	}
+	// random in [wait/2, wait)
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// request remote, the idempotent request will be retried if failed with transient error
+//
+// the error will be *RequestError
+// the request will be aborted once the ctx is done, and the retry will not be
+// started after the timeout since the first attempt
+//
+// the DELETE retried after a broken connection may have been applied by the
+// previous attempt, so the not found error of the retry is treated as success
+func (c *Client) request(ctx context.Context, method, path string, infos ...interface{}) (*req.Resp, error) {
 
 	// format url
 	url := c.formatURI(path)
 
-	password, _ := c.uri.User.Password()
+	retryable := isIdempotent(method) && !isConditional(infos)
 
-	header := req.Header{
-		keyCSRFTokenHeader: c.getToken(),
-		keyAuthorization:   basicAuth(c.uri.User.Username(), password),
-	}
+	infos = append(infos, ctx)
 
-	infos = append(infos, header)
+	deadline := time.Now().Add(c.timeout)
 
-	// do request
-	resp, err := c.req.Do(method, url, infos...)
+	// the previous attempt failed with broken connection
+	reset := false
+
+	for attempt := 0; ; attempt++ {
 
-	if resp != nil {
-
-		if isCSRFTokenError(resp.Response()) {
-			// try refresh csrf token
-			if err := c.fetchCSRFToken(); err != nil {
-				return nil, err
-			}
-			// update token
-			header[keyCSRFTokenHeader] = c.getToken()
-			// re process request
-			resp, err = c.req.Do(method, url, infos...)
+		resp, err := c.do(ctx, method, url, infos...)
+
+		if reset && method == "DELETE" && IsNotFound(err) {
+			log.Printf("request %v '%v' not found after retry, it was applied before", method, url)
+			return resp, nil
+		}
+
+		if ctx.Err() != nil || attempt >= c.retries || !retryable || !shouldRetry(err) {
+			// the response is kept, so that caller could inspect the error body
+			return resp, err
 		}
 
-		response := resp.Response()
+		reset = err.(*RequestError).Err != nil
 
-		switch {
-		case response.StatusCode > 400:
-			err = errors.New(response.Status)
+		wait := c.backoff(attempt)
+
+		if c.timeout > 0 && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+
+		log.Printf("request failed: %v, retry in %v", err, wait)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return resp, newTransportError(method, url, ctx.Err())
 		}
 
 	}
 
+}
+
+func (c *Client) do(ctx context.Context, method, url string, infos ...interface{}) (*req.Resp, error) {
+
+	header, err := c.header(ctx, c.getToken())
+
 	if err != nil {
 		return nil, err
 	}
 
-	return resp, err
+	infos = append(infos, header)
+
+	// do request
+	resp, err := c.req.Do(method, url, infos...)
+
+	if err != nil {
+		return resp, newTransportError(method, url, err)
+	}
+
+	// credential rejected, try refresh it
+	if resp.Response().StatusCode == http.StatusUnauthorized && c.auth.Refresh(ctx, c.req) {
+		if err := c.auth.Authorize(ctx, c.req, header); err != nil {
+			return nil, err
+		}
+		// re process request
+		if resp, err = c.req.Do(method, url, infos...); err != nil {
+			return resp, newTransportError(method, url, err)
+		}
+	}
+
+	if isCSRFTokenError(resp.Response()) {
+		// try refresh csrf token
+		if err := c.fetchCSRFToken(ctx); err != nil {
+			return nil, err
+		}
+		// update token
+		header[keyCSRFTokenHeader] = c.getToken()
+		// re process request
+		if resp, err = c.req.Do(method, url, infos...); err != nil {
+			return resp, newTransportError(method, url, err)
+		}
+	}
+
+	response := resp.Response()
+
+	if response.StatusCode >= 400 {
+		return resp, newStatusError(method, url, resp)
+	}
+
+	return resp, nil
 
 }
 
-func (c *Client) fetchCSRFToken() error {
+func (c *Client) fetchCSRFToken(ctx context.Context) error {
 
-	password, _ := c.uri.User.Password()
-	header := req.Header{
-		keyCSRFTokenHeader: "fetch",
-		keyAuthorization:   basicAuth(c.uri.User.Username(), password),
+	header, err := c.header(ctx, "fetch")
+
+	if err != nil {
+		return err
 	}
 
-	resp, err := c.req.Head(c.formatURI("/sap/hana/xs/dt/base/file"), header)
+	url := c.formatURI("/sap/hana/xs/dt/base/file")
+
+	resp, err := c.req.Head(url, header, ctx)
 
 	if err != nil {
 		return err
 	}
 
+	// credential rejected, try refresh it
+	if resp.Response().StatusCode == http.StatusUnauthorized && c.auth.Refresh(ctx, c.req) {
+		if err := c.auth.Authorize(ctx, c.req, header); err != nil {
+			return err
+		}
+		if resp, err = c.req.Head(url, header, ctx); err != nil {
+			return err
+		}
+	}
+
 	httpResponse := resp.Response()
 
 	status := httpResponse.StatusCode
@@ -194,15 +380,27 @@ func (c *Client) fetchCSRFToken() error {
 	return nil
 }
 
+// header of csrf token & credential
+func (c *Client) header(ctx context.Context, token string) (req.Header, error) {
+
+	rt := req.Header{keyCSRFTokenHeader: token}
+
+	if err := c.auth.Authorize(ctx, c.req, rt); err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
 func (c *Client) checkCredential() error {
-	if err := c.fetchCSRFToken(); err != nil {
+	if err := c.fetchCSRFToken(context.Background()); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (c *Client) checkURIValidate(uri *url.URL) error {
-	_, err := net.LookupHost(uri.Host)
+	_, err := net.LookupHost(uri.Hostname())
 	if err != nil {
 		return err
 	}
@@ -216,24 +414,63 @@ func (c *Client) formatDtFilePath(path string) string {
 
 // ReadFile content
 func (c *Client) ReadFile(filePath string) ([]byte, error) {
+	return c.ReadFileContext(context.Background(), filePath)
+}
+
+// ReadFileContext content, with context
+func (c *Client) ReadFileContext(ctx context.Context, filePath string) ([]byte, error) {
+	content, _, err := c.ReadFileIfNoneMatchContext(ctx, filePath, "")
+	return content, err
+}
+
+// ReadFileIfNoneMatch read content with etag
+func (c *Client) ReadFileIfNoneMatch(filePath, etag string) ([]byte, string, error) {
+	return c.ReadFileIfNoneMatchContext(context.Background(), filePath, etag)
+}
+
+// ReadFileIfNoneMatchContext read content with etag, with context
+//
+// if etag is not empty and the remote content not changed, ErrNotModified will be returned
+func (c *Client) ReadFileIfNoneMatchContext(ctx context.Context, filePath, etag string) ([]byte, string, error) {
+	header := req.Header{}
+
+	if len(etag) > 0 {
+		header[keyIfNoneMatch] = etag
+	}
+
 	res, err := c.request(
+		ctx,
 		"GET",
 		c.formatDtFilePath(filePath),
+		header,
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	response := res.Response()
+
+	if response.StatusCode == http.StatusNotModified {
+		return nil, etag, ErrNotModified
 	}
 
-	if res.Response().StatusCode == 404 {
-		return nil, ErrFileNotFound
+	content, err := res.ToBytes()
+
+	if err != nil {
+		return nil, "", err
 	}
 
-	return res.ToBytes()
+	return content, response.Header.Get(keyETag), nil
 }
 
 // Create file or directory
 func (c *Client) Create(base, name string, dir bool) error {
+	return c.CreateContext(context.Background(), base, name, dir)
+}
+
+// CreateContext file or directory, with context
+func (c *Client) CreateContext(ctx context.Context, base, name string, dir bool) error {
 
 	payload := map[string]interface{}{
 		"Name":      name,
@@ -241,6 +478,7 @@ func (c *Client) Create(base, name string, dir bool) error {
 	}
 
 	res, err := c.request(
+		ctx,
 		"POST",
 		c.formatDtFilePath(base),
 		req.BodyJSON(&payload),
@@ -260,11 +498,37 @@ func (c *Client) Create(base, name string, dir bool) error {
 
 // WriteFileContent to hana
 func (c *Client) WriteFileContent(path string, content []byte) (err error) {
+	return c.WriteFileContentContext(context.Background(), path, content)
+}
+
+// WriteFileContentContext to hana, with context
+func (c *Client) WriteFileContentContext(ctx context.Context, path string, content []byte) (err error) {
+	_, err = c.WriteFileContentIfMatchContext(ctx, path, content, "")
+	return err
+}
+
+// WriteFileContentIfMatch write content only if the remote etag not changed, return the new etag
+func (c *Client) WriteFileContentIfMatch(path string, content []byte, etag string) (string, error) {
+	return c.WriteFileContentIfMatchContext(context.Background(), path, content, etag)
+}
+
+// WriteFileContentIfMatchContext write content only if the remote etag not changed, return the new etag, with context
+//
+// if etag is not empty and the remote content has been changed, the error of KindPreconditionFailed will be returned
+func (c *Client) WriteFileContentIfMatchContext(ctx context.Context, path string, content []byte, etag string) (string, error) {
+
+	header := req.Header{}
+
+	if len(etag) > 0 {
+		header[keyIfMatch] = etag
+	}
 
 	res, err := c.request(
+		ctx,
 		"PUT",
 		c.formatDtFilePath(path),
 		content,
+		header,
 	)
 
 	if err == nil && res.Response().StatusCode >= 300 {
@@ -272,21 +536,27 @@ func (c *Client) WriteFileContent(path string, content []byte) (err error) {
 	}
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return res.Response().Header.Get(keyETag), nil
 
 }
 
 // ReadDirectory information
 func (c *Client) ReadDirectory(filePath string, depth int64) (*DirectoryDetail, error) {
+	return c.ReadDirectoryContext(context.Background(), filePath, depth)
+}
+
+// ReadDirectoryContext information, with context
+func (c *Client) ReadDirectoryContext(ctx context.Context, filePath string, depth int64) (*DirectoryDetail, error) {
 
 	if depth < 1 {
 		depth = 1
 	}
 
 	res, err := c.request(
+		ctx,
 		"GET",
 		c.formatDtFilePath(filePath),
 		req.QueryParam{"depth": depth},
@@ -296,10 +566,6 @@ func (c *Client) ReadDirectory(filePath string, depth int64) (*DirectoryDetail,
 		return nil, err
 	}
 
-	if res.Response().StatusCode == 404 {
-		return nil, ErrFileNotFound
-	}
-
 	rt := &DirectoryDetail{}
 
 	if err := res.ToJSON(rt); err != nil {
@@ -310,26 +576,119 @@ func (c *Client) ReadDirectory(filePath string, depth int64) (*DirectoryDetail,
 
 }
 
-func (c *Client) Delete(path string) (rt error) {
+// Activate objects in repository
+func (c *Client) Activate(paths ...string) error {
+	return c.ActivateContext(context.Background(), paths...)
+}
+
+// ActivateContext objects in repository, with context
+//
+// if the server rejected the activation, an *ActivationError with check messages will be returned
+func (c *Client) ActivateContext(ctx context.Context, paths ...string) error {
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	locations := []string{}
+
+	for _, p := range paths {
+		locations = append(locations, c.formatDtFilePath(p))
+	}
+
+	header := req.Header{
+		keyContentType: "application/json;charset=UTF-8",
+		keySapBackPack: `{"Activate":true}`,
+	}
+
+	res, err := c.request(ctx, "POST", "/sap/hana/xs/dt/base/file", req.BodyJSON(&locations), header)
+
+	if res != nil && isActivationFailure(res.Response()) {
+		return newActivationError(res)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// isActivationFailure response, the check messages are responded in json
+//
+// other errors (auth, server unavailable) keep the kind of *RequestError
+func isActivationFailure(response *http.Response) bool {
+	return (response.StatusCode == http.StatusBadRequest ||
+		response.StatusCode == http.StatusUnprocessableEntity) &&
+		strings.Contains(response.Header.Get(keyContentType), "json")
+}
+
+// Lock object for editing
+func (c *Client) Lock(path string) error {
+	return c.LockContext(context.Background(), path)
+}
+
+// LockContext object for editing, with context
+//
+// if the object is locked by others, a *LockedError will be returned
+func (c *Client) LockContext(ctx context.Context, path string) error {
+	return c.lockRequest(ctx, path, `{"Lock":true}`)
+}
+
+// Unlock object
+func (c *Client) Unlock(path string) error {
+	return c.UnlockContext(context.Background(), path)
+}
+
+// UnlockContext object, with context
+func (c *Client) UnlockContext(ctx context.Context, path string) error {
+	return c.lockRequest(ctx, path, `{"Unlock":true}`)
+}
+
+func (c *Client) lockRequest(ctx context.Context, path, backPack string) error {
 
 	res, err := c.request(
-		"DELETE",
+		ctx,
+		"POST",
 		c.formatDtFilePath(path),
+		req.Header{keySapBackPack: backPack},
 	)
 
-	if res.Response().StatusCode == 404 {
-		return ErrFileNotFound
+	if res != nil && res.Response().StatusCode == http.StatusLocked {
+		return newLockedError(path, res)
 	}
 
-	if err != nil {
-		return err
+	if err == nil && res.Response().StatusCode >= 300 {
+		err = errors.New(res.Response().Status)
 	}
 
+	return err
+}
+
+// Delete file or directory
+func (c *Client) Delete(path string) (rt error) {
+	return c.DeleteContext(context.Background(), path)
+}
+
+// DeleteContext file or directory, with context
+func (c *Client) DeleteContext(ctx context.Context, path string) (rt error) {
+
+	_, rt = c.request(
+		ctx,
+		"DELETE",
+		c.formatDtFilePath(path),
+	)
+
 	return
 }
 
 // Stat func
 func (c *Client) Stat(filePath string) (*PathStat, error) {
+	return c.StatContext(context.Background(), filePath)
+}
+
+// StatContext func, with context
+func (c *Client) StatContext(ctx context.Context, filePath string) (*PathStat, error) {
 
 	rt := &PathStat{}
 
@@ -339,6 +698,7 @@ func (c *Client) Stat(filePath string) (*PathStat, error) {
 	}
 
 	res, err := c.request(
+		ctx,
 		"GET",
 		c.formatDtFilePath(filePath),
 		query,
@@ -348,12 +708,12 @@ func (c *Client) Stat(filePath string) (*PathStat, error) {
 		return nil, err
 	}
 
-	if res.Response().StatusCode == 404 {
-		return nil, ErrFileNotFound
-	}
-
 	body, err := res.ToString()
 
+	if err != nil {
+		return nil, err
+	}
+
 	if gjson.Get(body, "Directory").Bool() {
 
 		dir := &DirectoryMeta{}
@@ -382,23 +742,94 @@ func (c *Client) Stat(filePath string) (*PathStat, error) {
 		rt.ReadOnly = f.Attributes.ReadOnly
 		rt.SymbolicLink = f.Attributes.SymbolicLink
 		rt.Activated = f.Attributes.SapBackPack.Activated
+		rt.ETag = f.ETag
+		rt.ActivatedBy = f.SapBackPack.ActivatedBy
+		rt.Version = f.SapBackPack.Version
+		rt.ObjectStatus = f.SapBackPack.ObjectStatus
+		rt.ContentType = f.ContentType
 
 		rt.TimeStamp = f.SapBackPack.ActivatedAt
 
+		// size from metadata, or the content length of HEAD request
+		if length := gjson.Get(body, "Length"); length.Exists() {
+			rt.Size = length.Int()
+		} else if size, err := c.FileSizeContext(ctx, filePath); err == nil {
+			rt.Size = size
+		} else {
+			rt.Size = -1
+		}
+
 	}
 
 	return rt, nil
 }
 
-// NewClient for hana
+// FileSize by the content length of HEAD request, without downloading the content
+func (c *Client) FileSize(filePath string) (int64, error) {
+	return c.FileSizeContext(context.Background(), filePath)
+}
+
+// FileSizeContext by the content length of HEAD request, without downloading the content, with context
+func (c *Client) FileSizeContext(ctx context.Context, filePath string) (int64, error) {
+
+	res, err := c.request(
+		ctx,
+		"HEAD",
+		c.formatDtFilePath(filePath),
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	response := res.Response()
+
+	if response.ContentLength < 0 {
+		return 0, ErrSizeUnknown
+	}
+
+	return response.ContentLength, nil
+}
+
+// NewClient for hana, with default options
 func NewClient(uri *url.URL) (*Client, error) {
-	rt := &Client{uri: uri, req: req.New(), baseDirectory: uri.Path, sslVerify: true}
+	return NewClientWithOptions(uri, nil)
+}
+
+// NewClientWithOptions for hana, default options will be used if opts is nil
+func NewClientWithOptions(uri *url.URL, opts *ClientOptions) (*Client, error) {
+
+	if opts == nil {
+		opts = DefaultClientOptions()
+	}
+
+	rt := &Client{
+		uri:           uri,
+		req:           req.New(),
+		baseDirectory: uri.Path,
+		sslVerify:     !opts.Insecure,
+		retries:       DefaultRetries,
+		retryBackoff:  DefaultRetryBackoff,
+		auth:          opts.Auth,
+	}
+
+	// basic authentication with the user info of uri by default
+	if rt.auth == nil {
+		password, _ := uri.User.Password()
+		rt.auth = &BasicAuth{User: uri.User.Username(), Password: password}
+	}
+
+	rt.SetTimeout(DefaultTimeout)
 
-	if err := rt.checkURIValidate(uri); err != nil {
+	tlsConfig, err := opts.tlsConfig()
+
+	if err != nil {
 		return nil, err
 	}
 
-	if err := rt.checkCredential(); err != nil {
+	proxy, err := opts.proxy()
+
+	if err != nil {
 		return nil, err
 	}
 
@@ -408,7 +839,30 @@ func NewClient(uri *url.URL) (*Client, error) {
 
 	trans.MaxIdleConns = 50
 	trans.TLSHandshakeTimeout = 20 * time.Second
-	trans.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	tlsConfig.InsecureSkipVerify = !rt.sslVerify
+	trans.TLSClientConfig = tlsConfig
+	trans.Proxy = proxy
+
+	proxyURL, err := rt.ProxyURL()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL != nil {
+		log.Printf("endpoint: %v, proxy: %v", rt.Endpoint(), redactURL(proxyURL))
+	} else {
+		log.Printf("endpoint: %v", rt.Endpoint())
+		// the target host maybe only resolvable by proxy
+		if err := rt.checkURIValidate(uri); err != nil {
+			return nil, err
+		}
+	}
+
+	// the transport must be configured before the first request
+	if err := rt.checkCredential(); err != nil {
+		return nil, err
+	}
 
 	return rt, nil
 }
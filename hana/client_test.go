@@ -0,0 +1,349 @@
+package hana_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Soontao/hanafs/hana"
+	"github.com/Soontao/hanafs/hana/hanatest"
+)
+
+func newTestClient(t *testing.T) (*hana.Client, *hanatest.Server) {
+	s := hanatest.NewServer()
+	s.WriteFile("/pkg/a.txt", []byte("hello"))
+	c, err := hana.NewClient(s.ClientURL("/pkg"))
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	return c, s
+}
+
+func TestReadWrite(t *testing.T) {
+	c, s := newTestClient(t)
+	defer s.Close()
+
+	content, err := c.ReadFile("/a.txt")
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("read %q, %v", content, err)
+	}
+
+	if err := c.WriteFileContent("/a.txt", []byte("world")); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "world" {
+		t.Errorf("remote content %q", b)
+	}
+
+	stat, err := c.Stat("/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Directory || stat.Size != 5 || len(stat.ETag) == 0 {
+		t.Errorf("unexpected stat %+v", stat)
+	}
+
+	if _, err := c.ReadFile("/b.txt"); !hana.IsNotFound(err) {
+		t.Errorf("not found expected, got %v", err)
+	}
+}
+
+func TestReadFileIfNoneMatch(t *testing.T) {
+	c, s := newTestClient(t)
+	defer s.Close()
+
+	content, etag, err := c.ReadFileIfNoneMatch("/a.txt", "")
+	if err != nil || string(content) != "hello" || len(etag) == 0 {
+		t.Fatalf("read %q, %q, %v", content, etag, err)
+	}
+
+	if _, same, err := c.ReadFileIfNoneMatch("/a.txt", etag); err != hana.ErrNotModified || same != etag {
+		t.Errorf("not modified expected, got %q, %v", same, err)
+	}
+
+	s.WriteFile("/pkg/a.txt", []byte("changed"))
+
+	if content, _, err := c.ReadFileIfNoneMatch("/a.txt", etag); err != nil || string(content) != "changed" {
+		t.Errorf("read %q, %v", content, err)
+	}
+}
+
+func TestWriteIfMatch(t *testing.T) {
+	c, s := newTestClient(t)
+	defer s.Close()
+
+	stat, err := c.Stat("/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	etag, err := c.WriteFileContentIfMatch("/a.txt", []byte("mine"), stat.ETag)
+	if err != nil || len(etag) == 0 || etag == stat.ETag {
+		t.Fatalf("write with current etag, got %q, %v", etag, err)
+	}
+
+	// stale etag
+	_, err = c.WriteFileContentIfMatch("/a.txt", []byte("stale"), stat.ETag)
+	if !hana.IsPreconditionFailed(err) {
+		t.Errorf("precondition failed expected, got %v", err)
+	}
+	if hana.IsConflict(err) {
+		t.Error("precondition failed should not be conflict")
+	}
+
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "mine" {
+		t.Errorf("remote content %q", b)
+	}
+}
+
+func TestLock(t *testing.T) {
+	c, s := newTestClient(t)
+	defer s.Close()
+
+	if err := c.Lock("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if holder := s.LockedBy("/pkg/a.txt"); holder != hanatest.DefaultUser {
+		t.Errorf("locked by %q", holder)
+	}
+
+	if err := c.Unlock("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if holder := s.LockedBy("/pkg/a.txt"); len(holder) > 0 {
+		t.Errorf("should be unlocked, locked by %q", holder)
+	}
+
+	s.LockAs("/pkg/a.txt", "BOB")
+
+	err := c.Lock("/a.txt")
+	if lErr, ok := err.(*hana.LockedError); !ok || lErr.LockedBy != "BOB" {
+		t.Errorf("locked error expected, got %v", err)
+	}
+}
+
+func TestActivate(t *testing.T) {
+	c, s := newTestClient(t)
+	defer s.Close()
+
+	if err := c.Activate("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsActivated("/pkg/a.txt") {
+		t.Error("should be activated")
+	}
+
+	s.WriteFile("/pkg/b.txt", []byte("invalid"))
+	s.SetActivationCheck(func(path string, content []byte) error {
+		return errors.New("syntax error")
+	})
+
+	err := c.Activate("/b.txt")
+	if _, ok := err.(*hana.ActivationError); !ok {
+		t.Errorf("activation error expected, got %v", err)
+	}
+	if s.IsActivated("/pkg/b.txt") {
+		t.Error("should not be activated")
+	}
+}
+
+// flakyServer in front of the server, the next failures requests are rejected with 503
+type flakyServer struct {
+	*httptest.Server
+	failures int32
+	requests int32
+}
+
+func newFlakyClient(t *testing.T, s *hanatest.Server) (*hana.Client, *flakyServer) {
+	flaky := &flakyServer{}
+	flaky.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&flaky.requests, 1)
+		if atomic.AddInt32(&flaky.failures, -1) >= 0 {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	uri := s.ClientURL("/pkg")
+	target, _ := url.Parse(flaky.URL)
+	uri.Host = target.Host
+	c, err := hana.NewClient(uri)
+	if err != nil {
+		flaky.Close()
+		t.Fatal(err)
+	}
+	return c, flaky
+}
+
+func TestRetryIdempotentRequest(t *testing.T) {
+	_, s := newTestClient(t)
+	defer s.Close()
+
+	c, flaky := newFlakyClient(t, s)
+	defer flaky.Close()
+
+	atomic.StoreInt32(&flaky.failures, 2)
+	atomic.StoreInt32(&flaky.requests, 0)
+
+	content, err := c.ReadFile("/a.txt")
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("read %q, %v", content, err)
+	}
+	if n := atomic.LoadInt32(&flaky.requests); n != 3 {
+		t.Errorf("requested %v times, want 3", n)
+	}
+
+	// give up after the max retries
+	atomic.StoreInt32(&flaky.failures, hana.DefaultRetries+1)
+	atomic.StoreInt32(&flaky.requests, 0)
+
+	if _, err := c.ReadFile("/a.txt"); !hana.IsServerError(err) {
+		t.Errorf("server error expected, got %v", err)
+	}
+	if n := atomic.LoadInt32(&flaky.requests); n != hana.DefaultRetries+1 {
+		t.Errorf("requested %v times, want %v", n, hana.DefaultRetries+1)
+	}
+}
+
+func TestNotRetryNonIdempotentRequest(t *testing.T) {
+	_, s := newTestClient(t)
+	defer s.Close()
+
+	c, flaky := newFlakyClient(t, s)
+	defer flaky.Close()
+
+	// fetch the csrf token before
+	if err := c.WriteFileContent("/a.txt", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&flaky.failures, 1)
+	atomic.StoreInt32(&flaky.requests, 0)
+
+	if err := c.Create("/", "b.txt", false); !hana.IsServerError(err) {
+		t.Errorf("server error expected, got %v", err)
+	}
+	if n := atomic.LoadInt32(&flaky.requests); n != 1 {
+		t.Errorf("requested %v times, want 1", n)
+	}
+}
+
+// resetServer in front of the server, the next resets requests are applied but
+// the connection is closed without response
+type resetServer struct {
+	*httptest.Server
+	resets   int32
+	requests int32
+}
+
+func newResetClient(t *testing.T, s *hanatest.Server) (*hana.Client, *resetServer) {
+	reset := &resetServer{}
+	reset.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reset.requests, 1)
+		if atomic.AddInt32(&reset.resets, -1) < 0 {
+			s.ServeHTTP(w, r)
+			return
+		}
+		s.ServeHTTP(httptest.NewRecorder(), r)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	uri := s.ClientURL("/pkg")
+	target, _ := url.Parse(reset.URL)
+	uri.Host = target.Host
+	c, err := hana.NewClient(uri)
+	if err != nil {
+		reset.Close()
+		t.Fatal(err)
+	}
+	return c, reset
+}
+
+func TestRetryDeleteAfterReset(t *testing.T) {
+	_, s := newTestClient(t)
+	defer s.Close()
+
+	c, reset := newResetClient(t, s)
+	defer reset.Close()
+
+	// fetch the csrf token before
+	if err := c.WriteFileContent("/a.txt", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&reset.resets, 1)
+	atomic.StoreInt32(&reset.requests, 0)
+
+	if err := c.Delete("/a.txt"); err != nil {
+		t.Errorf("applied delete should succeed, got %v", err)
+	}
+	if s.Exists("/pkg/a.txt") {
+		t.Error("file should be deleted")
+	}
+	if n := atomic.LoadInt32(&reset.requests); n != 2 {
+		t.Errorf("requested %v times, want 2", n)
+	}
+
+	// not found without reset is still reported
+	if err := c.Delete("/a.txt"); !hana.IsNotFound(err) {
+		t.Errorf("not found expected, got %v", err)
+	}
+}
+
+func TestNotRetryConditionalWrite(t *testing.T) {
+	_, s := newTestClient(t)
+	defer s.Close()
+
+	c, reset := newResetClient(t, s)
+	defer reset.Close()
+
+	etag, err := c.WriteFileContentIfMatch("/a.txt", []byte("x"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&reset.resets, 1)
+	atomic.StoreInt32(&reset.requests, 0)
+
+	if _, err := c.WriteFileContentIfMatch("/a.txt", []byte("y"), etag); err == nil || hana.IsPreconditionFailed(err) {
+		t.Errorf("connection error expected, got %v", err)
+	}
+	if n := atomic.LoadInt32(&reset.requests); n != 1 {
+		t.Errorf("requested %v times, want 1", n)
+	}
+	if b, _ := s.ReadFile("/pkg/a.txt"); string(b) != "y" {
+		t.Errorf("remote content %q", b)
+	}
+}
+
+func TestActivateRequestError(t *testing.T) {
+	_, s := newTestClient(t)
+	defer s.Close()
+
+	c, flaky := newFlakyClient(t, s)
+	defer flaky.Close()
+
+	// fetch the csrf token before
+	if err := c.WriteFileContent("/a.txt", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&flaky.failures, 1)
+
+	err := c.Activate("/a.txt")
+	if _, ok := err.(*hana.ActivationError); ok {
+		t.Errorf("unavailable server is not an activation error, got %v", err)
+	}
+	if !hana.IsServerError(err) {
+		t.Errorf("server error expected, got %v", err)
+	}
+}
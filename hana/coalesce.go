@@ -0,0 +1,170 @@
+package hana
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// inflightCall is a remote call in progress, or completed
+type inflightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+	// callers waiting the result, the call is cancelled once all of them gone
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// callGroup deduplicate the in-flight calls with same key
+type callGroup struct {
+	lock      sync.Mutex
+	calls     map[string]*inflightCall
+	coalesced int64
+}
+
+// detachedContext keeps the values of parent, but not the cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// do execute fn once for the concurrent callers of the same key,
+// all callers share the result
+//
+// the shared call is detached from the ctx of callers, a caller returns once its ctx
+// is done, and the call is cancelled only if all callers are gone
+func (g *callGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+
+	g.lock.Lock()
+
+	c, exist := g.calls[key]
+
+	if exist {
+		atomic.AddInt64(&g.coalesced, 1)
+	} else {
+		callCtx, cancel := context.WithCancel(detachedContext{ctx})
+		c = &inflightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go func() {
+			c.val, c.err = fn(callCtx)
+			g.lock.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.lock.Unlock()
+			cancel()
+			close(c.done)
+		}()
+	}
+
+	c.waiters++
+
+	g.lock.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		g.lock.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			// the later callers start a new call
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.lock.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// CoalescingBackend type
+//
+// concurrent identical read operations (same operation & path) share one remote call,
+// the write operations are passed through.
+//
+// the shared results MUST NOT be modified by callers, and the in-flight call is
+// cancelled only if the ctx of all callers are done
+type CoalescingBackend struct {
+	Backend
+	group *callGroup
+}
+
+// Coalesced count of the requests which shared the in-flight call
+func (b *CoalescingBackend) Coalesced() int64 {
+	return atomic.LoadInt64(&b.group.coalesced)
+}
+
+// StatContext file or directory metadata
+func (b *CoalescingBackend) StatContext(ctx context.Context, path string) (*PathStat, error) {
+	v, err := b.group.do(ctx, "stat:"+path, func(ctx context.Context) (interface{}, error) {
+		return b.Backend.StatContext(ctx, path)
+	})
+	rt, _ := v.(*PathStat)
+	return rt, err
+}
+
+// ReadDirectoryContext children information with depth
+func (b *CoalescingBackend) ReadDirectoryContext(ctx context.Context, path string, depth int64) (*DirectoryDetail, error) {
+	v, err := b.group.do(ctx, fmt.Sprintf("dir:%d:%s", depth, path), func(ctx context.Context) (interface{}, error) {
+		return b.Backend.ReadDirectoryContext(ctx, path, depth)
+	})
+	rt, _ := v.(*DirectoryDetail)
+	return rt, err
+}
+
+// FileSizeContext without downloading content
+func (b *CoalescingBackend) FileSizeContext(ctx context.Context, path string) (int64, error) {
+	v, err := b.group.do(ctx, "size:"+path, func(ctx context.Context) (interface{}, error) {
+		return b.Backend.FileSizeContext(ctx, path)
+	})
+	rt, _ := v.(int64)
+	return rt, err
+}
+
+// ReadFileContext content
+func (b *CoalescingBackend) ReadFileContext(ctx context.Context, path string) ([]byte, error) {
+	v, err := b.group.do(ctx, "read:"+path, func(ctx context.Context) (interface{}, error) {
+		return b.Backend.ReadFileContext(ctx, path)
+	})
+	rt, _ := v.([]byte)
+	return rt, err
+}
+
+type contentWithETag struct {
+	content []byte
+	etag    string
+}
+
+// ReadFileIfNoneMatchContext read content if the etag changed
+func (b *CoalescingBackend) ReadFileIfNoneMatchContext(ctx context.Context, path, etag string) ([]byte, string, error) {
+	v, err := b.group.do(ctx, "read:"+etag+":"+path, func(ctx context.Context) (interface{}, error) {
+		content, newETag, err := b.Backend.ReadFileIfNoneMatchContext(ctx, path, etag)
+		return &contentWithETag{content, newETag}, err
+	})
+	if rt, ok := v.(*contentWithETag); ok {
+		return rt.content, rt.etag, err
+	}
+	return nil, "", err
+}
+
+// NewCoalescingBackend decorate the backend with request coalescing
+func NewCoalescingBackend(b Backend) *CoalescingBackend {
+	return &CoalescingBackend{
+		Backend: b,
+		group:   &callGroup{calls: map[string]*inflightCall{}},
+	}
+}
+
+var _ Backend = (*CoalescingBackend)(nil)
@@ -0,0 +1,137 @@
+package hana
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallGroupShareResult(t *testing.T) {
+	g := &callGroup{calls: map[string]*inflightCall{}}
+
+	calls := int32(0)
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.do(context.Background(), "k", func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "v", nil
+			})
+			if v != "v" || err != nil {
+				t.Errorf("got %v, %v", v, err)
+			}
+		}()
+	}
+
+	// wait all callers joined
+	for atomic.LoadInt64(&g.coalesced) < 4 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("called %v times", calls)
+	}
+
+	if len(g.calls) != 0 {
+		t.Errorf("completed call should be removed")
+	}
+}
+
+func TestCallGroupCancelOneCaller(t *testing.T) {
+	g := &callGroup{calls: map[string]*inflightCall{}}
+
+	release := make(chan struct{})
+	callErr := make(chan error, 1)
+
+	fn := func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-release:
+			return "v", nil
+		case <-ctx.Done():
+			callErr <- ctx.Err()
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "k", fn)
+		first <- err
+	}()
+
+	// wait the first caller started the call
+	for {
+		g.lock.Lock()
+		_, started := g.calls["k"]
+		g.lock.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan interface{}, 1)
+	go func() {
+		v, _ := g.do(context.Background(), "k", fn)
+		second <- v
+	}()
+
+	for atomic.LoadInt64(&g.coalesced) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the first caller gives up, the shared call continues for the second one
+	cancel()
+
+	if err := <-first; err != context.Canceled {
+		t.Errorf("first caller should be cancelled, got %v", err)
+	}
+
+	close(release)
+
+	if v := <-second; v != "v" {
+		t.Errorf("second caller should get the result, got %v", v)
+	}
+
+	select {
+	case err := <-callErr:
+		t.Errorf("shared call should not be cancelled, got %v", err)
+	default:
+	}
+}
+
+func TestCallGroupCancelAllCallers(t *testing.T) {
+	g := &callGroup{calls: map[string]*inflightCall{}}
+
+	callErr := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	g.do(ctx, "k", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		callErr <- ctx.Err()
+		return nil, ctx.Err()
+	})
+
+	select {
+	case err := <-callErr:
+		if err != context.Canceled {
+			t.Errorf("got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("call should be cancelled once all callers are gone")
+	}
+}
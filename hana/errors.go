@@ -1,7 +1,17 @@
 package hana
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/imroc/req"
 )
 
 // ErrFileNotFound error
@@ -9,3 +19,255 @@ var ErrFileNotFound = errors.New("File not found")
 
 // ErrOpNotAllowed error
 var ErrOpNotAllowed = errors.New("Operation not allowed")
+
+// ErrSizeUnknown error, server does not provide the content length
+var ErrSizeUnknown = errors.New("Size unknown")
+
+// ErrNotModified error, the remote content is not changed
+var ErrNotModified = errors.New("Not modified")
+
+// ActivationMessage is a check message of activation
+type ActivationMessage struct {
+	Location string `json:"Location"`
+	Severity string `json:"Severity"`
+	Message  string `json:"Message"`
+}
+
+// ActivationError error, server rejected the activation
+type ActivationError struct {
+	Status       string              `json:"-"`
+	ErrorMessage string              `json:"ErrorMessage"`
+	CheckResults []ActivationMessage `json:"CheckResults"`
+}
+
+func (e *ActivationError) Error() string {
+	messages := []string{}
+
+	if len(e.ErrorMessage) > 0 {
+		messages = append(messages, e.ErrorMessage)
+	}
+
+	for _, m := range e.CheckResults {
+		messages = append(messages, fmt.Sprintf("%v: %v: %v", m.Location, m.Severity, m.Message))
+	}
+
+	if len(messages) == 0 {
+		messages = append(messages, e.Status)
+	}
+
+	return fmt.Sprintf("activation failed: %v", strings.Join(messages, "; "))
+}
+
+func newActivationError(res *req.Resp) *ActivationError {
+	rt := &ActivationError{Status: res.Response().Status}
+	body, _ := res.ToBytes()
+	if err := json.Unmarshal(body, rt); err != nil {
+		rt.ErrorMessage = strings.TrimSpace(string(body))
+	}
+	return rt
+}
+
+// LockedError error, the object is locked by others
+type LockedError struct {
+	Path     string `json:"-"`
+	LockedBy string `json:"LockedBy"`
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("'%v' is locked by '%v'", e.Path, e.LockedBy)
+}
+
+func newLockedError(path string, res *req.Resp) *LockedError {
+	rt := &LockedError{Path: path}
+	body, _ := res.ToBytes()
+	json.Unmarshal(body, rt)
+	return rt
+}
+
+// ErrorKind of the request error
+type ErrorKind int
+
+const (
+	// KindUnknown is the unclassified error
+	KindUnknown ErrorKind = iota
+	// KindNotFound the object is not existed
+	KindNotFound
+	// KindForbidden the operation is not permitted for user
+	KindForbidden
+	// KindConflict the object is already existed
+	KindConflict
+	// KindUnauthorized the credential is not accepted
+	KindUnauthorized
+	// KindServerError the server is not available
+	KindServerError
+	// KindTimeout the request or server timeout
+	KindTimeout
+	// KindPreconditionFailed the remote content has been changed by others (If-Match)
+	KindPreconditionFailed
+)
+
+var errorKindNames = map[ErrorKind]string{
+	KindUnknown:            "unknown",
+	KindNotFound:           "not found",
+	KindForbidden:          "forbidden",
+	KindConflict:           "conflict",
+	KindUnauthorized:       "unauthorized",
+	KindServerError:        "server error",
+	KindTimeout:            "timeout",
+	KindPreconditionFailed: "precondition failed",
+}
+
+func (k ErrorKind) String() string {
+	return errorKindNames[k]
+}
+
+// maxErrorBodyLength in error message
+const maxErrorBodyLength = 256
+
+// RequestError error, the remote request failed
+type RequestError struct {
+	Kind   ErrorKind
+	Method string
+	URL    string
+	// StatusCode is 0 if no response received
+	StatusCode int
+	Status     string
+	// Body of error response
+	Body string
+	// Err is the transport error
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v %v: %v", e.Method, e.URL, e.Err)
+	}
+	body := strings.TrimSpace(e.Body)
+	if len(body) > maxErrorBodyLength {
+		body = body[:maxErrorBodyLength] + "..."
+	}
+	if len(body) > 0 {
+		return fmt.Sprintf("%v %v: %v: %v", e.Method, e.URL, e.Status, body)
+	}
+	return fmt.Sprintf("%v %v: %v", e.Method, e.URL, e.Status)
+}
+
+// statusErrorKind of http status code
+func statusErrorKind(status int) ErrorKind {
+	switch {
+	case status == http.StatusNotFound:
+		return KindNotFound
+	case status == http.StatusUnauthorized:
+		return KindUnauthorized
+	case status == http.StatusForbidden:
+		return KindForbidden
+	case status == http.StatusConflict:
+		return KindConflict
+	case status == http.StatusPreconditionFailed:
+		return KindPreconditionFailed
+	case status == http.StatusRequestTimeout, status == http.StatusGatewayTimeout:
+		return KindTimeout
+	case status >= 500:
+		return KindServerError
+	default:
+		return KindUnknown
+	}
+}
+
+func newStatusError(method, url string, res *req.Resp) *RequestError {
+	response := res.Response()
+	body, _ := res.ToBytes()
+	return &RequestError{
+		Kind:       statusErrorKind(response.StatusCode),
+		Method:     method,
+		URL:        url,
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Body:       string(body),
+	}
+}
+
+func newTransportError(method, url string, err error) *RequestError {
+	rt := &RequestError{Kind: KindUnknown, Method: method, URL: url, Err: err}
+	if isTimeout(err) {
+		rt.Kind = KindTimeout
+	}
+	return rt
+}
+
+// isTimeout check the transport error is timeout
+func isTimeout(err error) bool {
+	if uErr, ok := err.(*url.Error); ok {
+		err = uErr.Err
+	}
+	if nErr, ok := err.(net.Error); ok && nErr.Timeout() {
+		return true
+	}
+	return err == context.DeadlineExceeded
+}
+
+// isConnectionReset check the transport error is caused by broken connection
+func isConnectionReset(err error) bool {
+	if uErr, ok := err.(*url.Error); ok {
+		err = uErr.Err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset") || strings.Contains(msg, "broken pipe")
+}
+
+func errorKind(err error) ErrorKind {
+	if rErr, ok := err.(*RequestError); ok {
+		return rErr.Kind
+	}
+	return KindUnknown
+}
+
+// IsNotFound check the error means object not existed
+func IsNotFound(err error) bool {
+	return err == ErrFileNotFound || errorKind(err) == KindNotFound
+}
+
+// IsForbidden check the error means permission denied
+func IsForbidden(err error) bool {
+	return errorKind(err) == KindForbidden
+}
+
+// IsUnauthorized check the error means credential rejected
+func IsUnauthorized(err error) bool {
+	return errorKind(err) == KindUnauthorized
+}
+
+// IsConflict check the error means object already existed
+func IsConflict(err error) bool {
+	return errorKind(err) == KindConflict
+}
+
+// IsPreconditionFailed check the error means the remote content has been changed by others
+func IsPreconditionFailed(err error) bool {
+	return errorKind(err) == KindPreconditionFailed
+}
+
+// IsServerError check the error is caused by server
+func IsServerError(err error) bool {
+	return errorKind(err) == KindServerError
+}
+
+// IsCanceled check the request is aborted by the cancelled context
+func IsCanceled(err error) bool {
+	rErr, ok := err.(*RequestError)
+	if !ok || rErr.Err == nil {
+		return false
+	}
+	if uErr, ok := rErr.Err.(*url.Error); ok {
+		return uErr.Err == context.Canceled
+	}
+	return rErr.Err == context.Canceled
+}
+
+// IsTimeout check the error is timeout
+func IsTimeout(err error) bool {
+	return errorKind(err) == KindTimeout
+}
@@ -0,0 +1,840 @@
+// Package hanatest provides an in-memory hana xs dt file api server,
+// so that the client and the file system could be exercised without a real tenant.
+//
+//	server := hanatest.NewServer()
+//	defer server.Close()
+//	server.WriteFile("/pkg/a.txt", []byte("content"))
+//	client, err := hana.NewClient(server.ClientURL("/pkg"))
+package hanatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Soontao/hanafs/hana"
+)
+
+// FilePrefix of xs dt file api
+const FilePrefix = "/sap/hana/xs/dt/base/file"
+
+const keyCSRFTokenHeader = "x-csrf-token"
+
+const keyCreateOptions = "X-Create-Options"
+
+const keySapBackPack = "SapBackPack"
+
+// DefaultUser used when the request has no credential
+const DefaultUser = "SYSTEM"
+
+type node struct {
+	name         string
+	dir          bool
+	deliveryUnit string
+	content      []byte
+	children     map[string]*node
+	version      int64
+	etag         string
+	activated    bool
+	activatedBy  string
+	timestamp    int64
+}
+
+func newNode(name string, dir bool) *node {
+	rt := &node{name: name, dir: dir, timestamp: nowMillis()}
+	if dir {
+		rt.children = map[string]*node{}
+	}
+	return rt
+}
+
+func (n *node) sortedChildren() (rt []*node) {
+	for _, c := range n.children {
+		rt = append(rt, c)
+	}
+	sort.Slice(rt, func(i, j int) bool { return rt[i].name < rt[j].name })
+	return rt
+}
+
+func (n *node) setContent(content []byte, user string) {
+	n.content = content
+	n.version++
+	n.etag = fmt.Sprintf("%08x-%d", crc32.ChecksumIEEE(content), n.version)
+	n.activated = false
+	n.activatedBy = user
+	n.timestamp = nowMillis()
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// Server is an in-memory implementation of hana xs dt file api
+type Server struct {
+	*httptest.Server
+	lock  sync.RWMutex
+	root  *node
+	users map[string]string
+	token string
+	check ActivationCheck
+	// path -> lock holder
+	locks map[string]string
+	// bearer token -> user
+	bearers map[string]string
+	// session cookie value -> user
+	sessions map[string]string
+}
+
+// ActivationCheck validate the object before activation, return error to reject it
+type ActivationCheck func(path string, content []byte) error
+
+// NewServer start a fake server with an empty repository
+func NewServer() *Server {
+	rt := NewUnstartedServer()
+	rt.Start()
+	return rt
+}
+
+// NewUnstartedServer create a fake server without starting it,
+// the caller should call Start or StartTLS
+func NewUnstartedServer() *Server {
+	rt := &Server{
+		root:  newNode("", true),
+		users: map[string]string{},
+		locks: map[string]string{},
+		token: strconv.FormatInt(time.Now().UnixNano(), 36),
+
+		bearers:  map[string]string{},
+		sessions: map[string]string{},
+	}
+	rt.Server = httptest.NewUnstartedServer(rt)
+	return rt
+}
+
+// SetCredential require the basic auth credential, by default any credential is accepted
+func (s *Server) SetCredential(user, password string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.users[user] = password
+}
+
+// SetBearerToken accept the bearer token as user, empty user to revoke it
+func (s *Server) SetBearerToken(token, user string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(user) == 0 {
+		delete(s.bearers, token)
+		return
+	}
+	s.bearers[token] = user
+}
+
+// SetSessionCookie accept the session cookie (MYSAPSSO2 or xsSecureId) value as user, empty user to revoke it
+func (s *Server) SetSessionCookie(value, user string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(user) == 0 {
+		delete(s.sessions, value)
+		return
+	}
+	s.sessions[value] = user
+}
+
+// SetActivationCheck for activation, by default all objects could be activated
+func (s *Server) SetActivationCheck(check ActivationCheck) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.check = check
+}
+
+// SetDeliveryUnit of package (directory)
+func (s *Server) SetDeliveryUnit(p string, deliveryUnit string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.mkdirAll(p).deliveryUnit = deliveryUnit
+}
+
+// LockAs lock the object as user, to simulate the editing of others
+func (s *Server) LockAs(p string, user string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.locks[cleanPath(p)] = user
+}
+
+// LockedBy return the lock holder of object, empty if not locked
+func (s *Server) LockedBy(p string) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.locks[cleanPath(p)]
+}
+
+// IsActivated check the object is activated or not
+func (s *Server) IsActivated(p string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	n := s.lookup(p)
+	return n != nil && n.activated
+}
+
+// ClientURL for hana.NewClient, with base directory and the first credential
+func (s *Server) ClientURL(base string) *url.URL {
+	rt, _ := url.Parse(s.URL)
+	rt.Path = base
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	rt.User = url.User(DefaultUser)
+	for user, password := range s.users {
+		rt.User = url.UserPassword(user, password)
+		break
+	}
+
+	return rt
+}
+
+// MkdirAll create directory and all parents
+func (s *Server) MkdirAll(p string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.mkdirAll(p)
+}
+
+// WriteFile create or overwrite file, parents will be created
+func (s *Server) WriteFile(p string, content []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	dir, name := path.Split(cleanPath(p))
+	parent := s.mkdirAll(dir)
+
+	f, exist := parent.children[name]
+	if !exist {
+		f = newNode(name, false)
+		parent.children[name] = f
+	}
+
+	f.setContent(append([]byte{}, content...), DefaultUser)
+}
+
+// ReadFile content from repository
+func (s *Server) ReadFile(p string) ([]byte, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	n := s.lookup(p)
+	if n == nil || n.dir {
+		return nil, false
+	}
+
+	return append([]byte{}, n.content...), true
+}
+
+// Exists check file or directory in repository
+func (s *Server) Exists(p string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.lookup(p) != nil
+}
+
+func cleanPath(p string) string {
+	return path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+}
+
+func (s *Server) mkdirAll(p string) *node {
+	current := s.root
+	for _, name := range strings.Split(cleanPath(p), "/") {
+		if len(name) == 0 {
+			continue
+		}
+		c, exist := current.children[name]
+		if !exist || !c.dir {
+			c = newNode(name, true)
+			current.children[name] = c
+		}
+		current = c
+	}
+	return current
+}
+
+func (s *Server) lookup(p string) *node {
+	current := s.root
+	for _, name := range strings.Split(cleanPath(p), "/") {
+		if len(name) == 0 {
+			continue
+		}
+		if !current.dir {
+			return nil
+		}
+		c, exist := current.children[name]
+		if !exist {
+			return nil
+		}
+		current = c
+	}
+	return current
+}
+
+func (s *Server) lookupParent(p string) (*node, string) {
+	dir, name := path.Split(cleanPath(p))
+	parent := s.lookup(dir)
+	if parent == nil || !parent.dir {
+		return nil, name
+	}
+	return parent, name
+}
+
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+
+	// client certificate authentication, the common name is the user
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates[0].Subject.CommonName, true
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		user, exist := s.bearers[strings.TrimPrefix(auth, "Bearer ")]
+		return user, exist
+	}
+
+	for _, name := range []string{"MYSAPSSO2", "xsSecureId"} {
+		if c, err := r.Cookie(name); err == nil {
+			if user, exist := s.sessions[c.Value]; exist {
+				return user, true
+			}
+		}
+	}
+
+	user, password, ok := r.BasicAuth()
+
+	if len(s.users) == 0 {
+		if !ok || len(user) == 0 {
+			user = DefaultUser
+		}
+		return user, true
+	}
+
+	if expected, exist := s.users[user]; ok && exist && expected == password {
+		return user, true
+	}
+
+	return "", false
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := s.authenticate(r)
+
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="xs"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, FilePrefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.ToLower(r.Header.Get(keyCSRFTokenHeader)) == "fetch" {
+		w.Header().Set(keyCSRFTokenHeader, s.token)
+	}
+
+	repoPath := cleanPath(strings.TrimPrefix(r.URL.Path, FilePrefix))
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.serveRead(w, r, repoPath)
+		return
+	}
+
+	if r.Header.Get(keyCSRFTokenHeader) != s.token {
+		w.Header().Set(keyCSRFTokenHeader, "Required")
+		http.Error(w, "CSRF token validation failed", http.StatusForbidden)
+		return
+	}
+
+	backPack := r.Header.Get(keySapBackPack)
+
+	switch {
+	case r.Method == http.MethodPost && strings.Contains(backPack, `"Lock":true`):
+		s.serveLock(w, r, repoPath, user, true)
+		return
+	case r.Method == http.MethodPost && strings.Contains(backPack, `"Unlock":true`):
+		s.serveLock(w, r, repoPath, user, false)
+		return
+	case r.Method == http.MethodPut || r.Method == http.MethodDelete:
+		if holder := s.LockedBy(repoPath); len(holder) > 0 && holder != user {
+			writeJSON(w, http.StatusLocked, &hana.LockedError{LockedBy: holder})
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.serveWrite(w, r, repoPath, user)
+	case http.MethodPost:
+		if strings.Contains(backPack, `"Activate":true`) {
+			s.serveActivate(w, r, user)
+		} else {
+			s.serveCreate(w, r, repoPath, user)
+		}
+	case http.MethodDelete:
+		s.serveDelete(w, r, repoPath)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+
+}
+
+func (s *Server) serveRead(w http.ResponseWriter, r *http.Request, repoPath string) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	n := s.lookup(repoPath)
+
+	if n == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if query.Get("parts") == "meta" {
+		if n.dir {
+			writeJSON(w, http.StatusOK, s.directoryMeta(repoPath, n))
+		} else {
+			writeJSON(w, http.StatusOK, s.fileMeta(repoPath, n))
+		}
+		return
+	}
+
+	if n.dir {
+		depth := int64(1)
+		if v, err := strconv.ParseInt(query.Get("depth"), 10, 64); err == nil {
+			depth = v
+		}
+		writeJSON(w, http.StatusOK, s.directoryDetail(repoPath, n, depth))
+		return
+	}
+
+	w.Header().Set("ETag", n.etag)
+
+	if match := r.Header.Get("If-None-Match"); len(match) > 0 && match == n.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType(n.name))
+	w.Header().Set("Content-Length", strconv.Itoa(len(n.content)))
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		w.Write(n.content)
+	}
+}
+
+func (s *Server) serveWrite(w http.ResponseWriter, r *http.Request, repoPath, user string) {
+	content, err := ioutil.ReadAll(r.Body)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := s.lookup(repoPath)
+
+	if n == nil || n.dir {
+		http.NotFound(w, r)
+		return
+	}
+
+	if match := r.Header.Get("If-Match"); len(match) > 0 && match != n.etag {
+		http.Error(w, "Precondition Failed", http.StatusPreconditionFailed)
+		return
+	}
+
+	n.setContent(content, user)
+
+	w.Header().Set("ETag", n.etag)
+	writeJSON(w, http.StatusOK, s.fileMeta(repoPath, n))
+}
+
+type createPayload struct {
+	Name      string `json:"Name"`
+	Directory bool   `json:"Directory"`
+	Location  string `json:"Location"`
+	Target    string `json:"Target"`
+}
+
+func (s *Server) serveCreate(w http.ResponseWriter, r *http.Request, repoPath, user string) {
+	payload := &createPayload{}
+
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options := map[string]bool{}
+	for _, o := range strings.Split(r.Header.Get(keyCreateOptions), ",") {
+		options[strings.TrimSpace(strings.ToLower(o))] = true
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parent := s.lookup(repoPath)
+
+	if parent == nil || !parent.dir {
+		http.NotFound(w, r)
+		return
+	}
+
+	if options["move"] || options["copy"] {
+		s.serveMove(w, r, repoPath, parent, payload, options)
+		return
+	}
+
+	if len(payload.Name) == 0 || strings.Contains(payload.Name, "/") {
+		http.Error(w, "invalid name", http.StatusBadRequest)
+		return
+	}
+
+	if _, exist := parent.children[payload.Name]; exist {
+		http.Error(w, "resource already exists", http.StatusConflict)
+		return
+	}
+
+	n := newNode(payload.Name, payload.Directory)
+	if !n.dir {
+		n.setContent([]byte{}, user)
+	}
+	parent.children[payload.Name] = n
+
+	created := path.Join(repoPath, payload.Name)
+
+	if n.dir {
+		writeJSON(w, http.StatusCreated, s.directoryMeta(created, n))
+	} else {
+		writeJSON(w, http.StatusCreated, s.fileMeta(created, n))
+	}
+}
+
+func (s *Server) serveMove(w http.ResponseWriter, r *http.Request, repoPath string, parent *node, payload *createPayload, options map[string]bool) {
+	sourcePath := cleanPath(strings.TrimPrefix(payload.Location, FilePrefix))
+	source := s.lookup(sourcePath)
+
+	if source == nil || sourcePath == "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	target := payload.Target
+	if len(target) == 0 {
+		target = source.name
+	}
+
+	if _, exist := parent.children[target]; exist && options["no-overwrite"] {
+		http.Error(w, "target already exists", http.StatusPreconditionFailed)
+		return
+	}
+
+	if strings.HasPrefix(path.Join(repoPath, target)+"/", sourcePath+"/") {
+		http.Error(w, "could not move directory into itself", http.StatusBadRequest)
+		return
+	}
+
+	if options["move"] {
+		sourceParent, sourceName := s.lookupParent(sourcePath)
+		delete(sourceParent.children, sourceName)
+	} else {
+		source = copyNode(source)
+	}
+
+	source.name = target
+	parent.children[target] = source
+
+	moved := path.Join(repoPath, target)
+
+	if source.dir {
+		writeJSON(w, http.StatusCreated, s.directoryMeta(moved, source))
+	} else {
+		writeJSON(w, http.StatusCreated, s.fileMeta(moved, source))
+	}
+}
+
+func (s *Server) serveLock(w http.ResponseWriter, r *http.Request, repoPath, user string, lock bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if n := s.lookup(repoPath); n == nil || n.dir {
+		http.NotFound(w, r)
+		return
+	}
+
+	holder, locked := s.locks[repoPath]
+
+	if locked && holder != user {
+		writeJSON(w, http.StatusLocked, &hana.LockedError{LockedBy: holder})
+		return
+	}
+
+	if lock {
+		s.locks[repoPath] = user
+	} else {
+		delete(s.locks, repoPath)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveActivate(w http.ResponseWriter, r *http.Request, user string) {
+	locations := []string{}
+
+	if err := json.NewDecoder(r.Body).Decode(&locations); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := &hana.ActivationError{}
+	objects := []*node{}
+
+	for _, l := range locations {
+		n := s.lookup(strings.TrimPrefix(l, FilePrefix))
+		switch {
+		case n == nil || n.dir:
+			result.CheckResults = append(result.CheckResults, hana.ActivationMessage{
+				Location: l, Severity: "error", Message: "object not found",
+			})
+		case s.check != nil:
+			if err := s.check(cleanPath(strings.TrimPrefix(l, FilePrefix)), n.content); err != nil {
+				result.CheckResults = append(result.CheckResults, hana.ActivationMessage{
+					Location: l, Severity: "error", Message: err.Error(),
+				})
+				continue
+			}
+			fallthrough
+		default:
+			objects = append(objects, n)
+		}
+	}
+
+	if len(result.CheckResults) > 0 {
+		result.ErrorMessage = "Activation failed"
+		writeJSON(w, http.StatusBadRequest, result)
+		return
+	}
+
+	for _, n := range objects {
+		n.activated = true
+		n.activatedBy = user
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"Activated": locations})
+}
+
+func copyNode(n *node) *node {
+	rt := *n
+	rt.content = append([]byte{}, n.content...)
+	if n.dir {
+		rt.children = map[string]*node{}
+		for name, c := range n.children {
+			rt.children[name] = copyNode(c)
+		}
+	}
+	return &rt
+}
+
+func (s *Server) serveDelete(w http.ResponseWriter, r *http.Request, repoPath string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parent, name := s.lookupParent(repoPath)
+
+	if parent == nil || repoPath == "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, exist := parent.children[name]; !exist {
+		http.NotFound(w, r)
+		return
+	}
+
+	delete(parent.children, name)
+	delete(s.locks, repoPath)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func contentType(name string) string {
+	switch path.Ext(name) {
+	case ".json", ".xsjson", ".xsaccess", ".xsapp":
+		return "application/json"
+	case ".js", ".xsjs", ".xsjslib":
+		return "application/javascript"
+	case ".html":
+		return "text/html"
+	default:
+		return "text/plain"
+	}
+}
+
+func location(repoPath string) string {
+	if repoPath == "/" {
+		return FilePrefix
+	}
+	return FilePrefix + repoPath
+}
+
+func (s *Server) parents(repoPath string) (rt []hana.Parent) {
+	if repoPath == "/" {
+		return rt
+	}
+	for dir := path.Dir(repoPath); ; dir = path.Dir(dir) {
+		rt = append(rt, hana.Parent{
+			Name:             path.Base(dir),
+			Location:         location(dir),
+			ChildrenLocation: location(dir) + "?depth=1",
+		})
+		if dir == "/" {
+			return rt
+		}
+	}
+}
+
+func (s *Server) fileSapBackPack(n *node) hana.FileSapBackPack {
+	return hana.FileSapBackPack{
+		Version: n.version,
+		Type:    1,
+		// the client treats activated at as the modify time
+		ActivatedAt:  n.timestamp,
+		ActivatedBy:  n.activatedBy,
+		ObjectStatus: objectStatus(n),
+	}
+}
+
+func objectStatus(n *node) string {
+	if n.activated {
+		return "ACTIVE"
+	}
+	return "INACTIVE"
+}
+
+func (s *Server) fileAttributes(n *node) hana.Attributes {
+	return hana.Attributes{
+		SapBackPack: hana.AttributesSapBackPack{Activated: n.activated},
+	}
+}
+
+func (s *Server) fileMeta(repoPath string, n *node) *hana.File {
+	return &hana.File{
+		Name:           n.name,
+		Location:       location(repoPath),
+		RunLocation:    repoPath,
+		Directory:      false,
+		LocalTimeStamp: n.timestamp,
+		ContentType:    contentType(n.name),
+		Attributes:     s.fileAttributes(n),
+		ETag:           n.etag,
+		Parents:        s.parents(repoPath),
+		SapBackPack:    s.fileSapBackPack(n),
+	}
+}
+
+func (s *Server) directoryMeta(repoPath string, n *node) *hana.DirectoryMeta {
+	parents := []interface{}{}
+	for _, p := range s.parents(repoPath) {
+		parents = append(parents, p)
+	}
+	return &hana.DirectoryMeta{
+		Name:             n.name,
+		ID:               repoPath,
+		Location:         location(repoPath),
+		ContentLocation:  repoPath,
+		ChildrenLocation: location(repoPath) + "?depth=1",
+		Directory:        true,
+		Parents:          parents,
+		LocalTimeStamp:   n.timestamp,
+	}
+}
+
+func (s *Server) directoryDetail(repoPath string, n *node, depth int64) *hana.DirectoryDetail {
+	parents := []hana.DirectoryDetailParent{}
+	for _, p := range s.parents(repoPath) {
+		parents = append(parents, hana.DirectoryDetailParent(p))
+	}
+	backPack := hana.ChildSapBackPack{}
+	if len(n.deliveryUnit) > 0 {
+		deliveryUnit := n.deliveryUnit
+		backPack.DeliveryUnit = &deliveryUnit
+	}
+	return &hana.DirectoryDetail{
+		Name:             n.name,
+		ID:               repoPath,
+		Location:         location(repoPath),
+		ContentLocation:  repoPath,
+		ChildrenLocation: location(repoPath) + "?depth=1",
+		Directory:        true,
+		Parents:          parents,
+		SapBackPack:      backPack,
+		Children:         s.children(repoPath, n, depth),
+	}
+}
+
+func (s *Server) children(repoPath string, n *node, depth int64) []hana.Child {
+	rt := []hana.Child{}
+
+	if depth < 1 {
+		return rt
+	}
+
+	for _, c := range n.sortedChildren() {
+		childPath := path.Join(repoPath, c.name)
+		child := hana.Child{
+			Name:             c.name,
+			ID:               childPath,
+			Location:         location(childPath),
+			ContentLocation:  childPath,
+			ChildrenLocation: location(childPath) + "?depth=1",
+			Directory:        c.dir,
+		}
+		if c.dir {
+			child.Children = s.children(childPath, c, depth-1)
+		} else {
+			child.RunLocation = childPath
+			child.Attributes = s.fileAttributes(c)
+			// for file, the sap back pack is a serialized json string
+			backPack, _ := json.Marshal(s.fileSapBackPack(c))
+			child.SapBackPack = string(backPack)
+		}
+		rt = append(rt, child)
+	}
+
+	return rt
+}
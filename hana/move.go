@@ -0,0 +1,77 @@
+package hana
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+)
+
+// Move file or directory of backend, to the same directory or another package
+//
+// hana could not move object between packages, so that the object is copied to
+// target then deleted, the created targets will be removed if the copy failed
+func Move(ctx context.Context, b Backend, old, new string, dir bool) error {
+
+	oldDir, _ := filepath.Split(old)
+	newDir, _ := filepath.Split(new)
+
+	if oldDir == newDir {
+		return b.RenameContext(ctx, old, new, dir)
+	}
+
+	created := []string{}
+
+	if err := copyAcross(ctx, b, old, new, dir, &created); err != nil {
+		// rollback, deepest first
+		for i := len(created) - 1; i >= 0; i-- {
+			if e := b.DeleteContext(ctx, created[i]); e != nil {
+				log.Printf("rollback '%v' failed: %v", created[i], e)
+			}
+		}
+		return err
+	}
+
+	// the copy is complete, the target is kept whatever the source deletion result,
+	// the retried deletion maybe not found if the first attempt has been applied
+	if err := b.DeleteContext(ctx, old); err != nil && !IsNotFound(err) {
+		return fmt.Errorf("copied to '%v', but the source '%v' is left: %v", new, old, err)
+	}
+
+	return nil
+}
+
+// copyAcross file or directory (recursively), the created paths will be appended
+func copyAcross(ctx context.Context, b Backend, src, dst string, dir bool, created *[]string) error {
+
+	base, name := filepath.Split(dst)
+
+	if err := b.CreateContext(ctx, base, name, dir); err != nil {
+		return err
+	}
+
+	*created = append(*created, dst)
+
+	if !dir {
+		content, err := b.ReadFileContext(ctx, src)
+		if err != nil {
+			return err
+		}
+		return b.WriteFileContentContext(ctx, dst, content)
+	}
+
+	detail, err := b.ReadDirectoryContext(ctx, src, 1)
+
+	if err != nil {
+		return err
+	}
+
+	for _, child := range detail.Children {
+		if err := copyAcross(ctx, b, path.Join(src, child.Name), path.Join(dst, child.Name), child.Directory, created); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package hana_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Soontao/hanafs/hana"
+	"github.com/Soontao/hanafs/hana/hanatest"
+)
+
+func TestMoveAcrossPackages(t *testing.T) {
+	s := hanatest.NewServer()
+	defer s.Close()
+
+	s.WriteFile("/pkg/a/dir/x.txt", []byte("x"))
+	s.MkdirAll("/pkg/b")
+
+	c, err := hana.NewClient(s.ClientURL("/pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hana.Move(context.Background(), c, "/a/dir", "/b/dir", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Exists("/pkg/a/dir") {
+		t.Error("source should be deleted")
+	}
+	if b, _ := s.ReadFile("/pkg/b/dir/x.txt"); string(b) != "x" {
+		t.Errorf("moved content %q", b)
+	}
+}
+
+func TestMoveInSameDirectory(t *testing.T) {
+	s := hanatest.NewServer()
+	defer s.Close()
+
+	s.WriteFile("/pkg/a.txt", []byte("a"))
+
+	c, err := hana.NewClient(s.ClientURL("/pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hana.Move(context.Background(), c, "/a.txt", "/b.txt", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Exists("/pkg/a.txt") || !s.Exists("/pkg/b.txt") {
+		t.Error("file should be renamed")
+	}
+}
+
+// deleteFailure backend, the source is deleted on server but the error is returned
+type deleteFailure struct {
+	hana.Backend
+	applied bool
+	err     error
+}
+
+func (b *deleteFailure) DeleteContext(ctx context.Context, path string) error {
+	if path != "/a/x.txt" {
+		return b.Backend.DeleteContext(ctx, path)
+	}
+	if b.applied {
+		if err := b.Backend.DeleteContext(ctx, path); err != nil {
+			return err
+		}
+	}
+	return b.err
+}
+
+func TestMoveSourceDeleteFailed(t *testing.T) {
+	cases := []struct {
+		name    string
+		applied bool
+		err     error
+		failed  bool
+	}{
+		// the retried deletion after the first one applied
+		{"not found", true, &hana.RequestError{Kind: hana.KindNotFound, StatusCode: 404}, false},
+		{"connection reset", true, errors.New("connection reset"), true},
+		{"server error", false, &hana.RequestError{Kind: hana.KindServerError, StatusCode: 500}, true},
+	}
+
+	for _, c := range cases {
+		s := hanatest.NewServer()
+
+		s.WriteFile("/pkg/a/x.txt", []byte("x"))
+		s.MkdirAll("/pkg/b")
+
+		client, err := hana.NewClient(s.ClientURL("/pkg"))
+		if err != nil {
+			s.Close()
+			t.Fatal(err)
+		}
+
+		err = hana.Move(context.Background(), &deleteFailure{Backend: client, applied: c.applied, err: c.err}, "/a/x.txt", "/b/x.txt", false)
+
+		if (err != nil) != c.failed {
+			t.Errorf("%v: failed %v, got %v", c.name, c.failed, err)
+		}
+		if c.failed && !strings.Contains(fmt.Sprint(err), "left") {
+			t.Errorf("%v: the error should tell the source is left, got %v", c.name, err)
+		}
+		if b, _ := s.ReadFile("/pkg/b/x.txt"); string(b) != "x" {
+			t.Errorf("%v: target should be kept, got %q", c.name, b)
+		}
+		if s.Exists("/pkg/a/x.txt") == c.applied {
+			t.Errorf("%v: source existed %v", c.name, !c.applied)
+		}
+
+		s.Close()
+	}
+}
+
+// copyFailure backend, the content could not be written
+type copyFailure struct {
+	hana.Backend
+}
+
+func (b *copyFailure) WriteFileContentContext(ctx context.Context, path string, content []byte) error {
+	return errors.New("broken")
+}
+
+func TestMoveRollbackCopy(t *testing.T) {
+	s := hanatest.NewServer()
+	defer s.Close()
+
+	s.WriteFile("/pkg/a/x.txt", []byte("x"))
+	s.MkdirAll("/pkg/b")
+
+	client, err := hana.NewClient(s.ClientURL("/pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hana.Move(context.Background(), &copyFailure{client}, "/a/x.txt", "/b/x.txt", false); err == nil {
+		t.Fatal("move should fail")
+	}
+
+	if s.Exists("/pkg/b/x.txt") {
+		t.Error("created target should be removed")
+	}
+	if !s.Exists("/pkg/a/x.txt") {
+		t.Error("source should be kept")
+	}
+}
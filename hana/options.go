@@ -0,0 +1,98 @@
+package hana
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// ClientOptions of hana client
+type ClientOptions struct {
+	// CAFile is the PEM encoded certificates to verify the server, besides the system pool
+	CAFile string
+	// CertFile & KeyFile are the PEM encoded X.509 client certificate and private key
+	CertFile string
+	KeyFile  string
+	// Insecure skip the verification of server certificate
+	Insecure bool
+	// Proxy url, the proxy credential could be provided in user info,
+	// HTTPS_PROXY/HTTP_PROXY/NO_PROXY environment variables are used if empty
+	Proxy string
+	// Auth strategy, basic authentication with the user info of uri if nil
+	Auth Authenticator
+}
+
+// DefaultClientOptions for hana client
+func DefaultClientOptions() *ClientOptions {
+	return &ClientOptions{}
+}
+
+// proxy func of client options
+func (o *ClientOptions) proxy() (func(*http.Request) (*url.URL, error), error) {
+
+	if len(o.Proxy) == 0 {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(o.Proxy)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(proxyURL.Scheme) == 0 || len(proxyURL.Host) == 0 {
+		return nil, fmt.Errorf("invalid proxy url '%v'", o.Proxy)
+	}
+
+	return http.ProxyURL(proxyURL), nil
+}
+
+// tlsConfig of client options
+func (o *ClientOptions) tlsConfig() (*tls.Config, error) {
+
+	rt := &tls.Config{}
+
+	if len(o.CAFile) > 0 {
+
+		pem, err := ioutil.ReadFile(o.CAFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in '%v'", o.CAFile)
+		}
+
+		rt.RootCAs = pool
+
+	}
+
+	if len(o.CertFile) > 0 || len(o.KeyFile) > 0 {
+
+		if len(o.CertFile) == 0 || len(o.KeyFile) == 0 {
+			return nil, errors.New("both client certificate and key are required")
+		}
+
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rt.Certificates = []tls.Certificate{cert}
+
+	}
+
+	return rt, nil
+}
@@ -0,0 +1,215 @@
+package hana_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Soontao/hanafs/hana"
+	"github.com/Soontao/hanafs/hana/hanatest"
+)
+
+// writePEM file of blocks
+func writePEM(t *testing.T, path string, blocks ...*pem.Block) {
+	content := []byte{}
+	for _, b := range blocks {
+		content = append(content, pem.EncodeToMemory(b)...)
+	}
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeClientCertificate self signed, with the user as common name
+func writeClientCertificate(t *testing.T, certFile, keyFile, user string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: user},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, certFile, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	writePEM(t, keyFile, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestServerCertificateVerification(t *testing.T) {
+	s := hanatest.NewUnstartedServer()
+	s.StartTLS()
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "hanafs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, &pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+
+	// unknown authority
+	if _, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), nil); err == nil {
+		t.Error("server certificate should not be trusted without ca file")
+	}
+
+	if _, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), &hana.ClientOptions{Insecure: true}); err != nil {
+		t.Errorf("insecure client should skip verification, got %v", err)
+	}
+
+	if _, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), &hana.ClientOptions{CAFile: caFile}); err != nil {
+		t.Errorf("server certificate should be trusted with ca file, got %v", err)
+	}
+
+	// not a certificate
+	invalid := filepath.Join(dir, "invalid.pem")
+	if err := ioutil.WriteFile(invalid, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := hana.NewClientWithOptions(s.ClientURL("/pkg"), &hana.ClientOptions{CAFile: invalid}); err == nil {
+		t.Error("invalid ca file should be rejected")
+	}
+}
+
+func TestClientCertificateAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hanafs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	cert := writeClientCertificate(t, certFile, keyFile, "ALICE")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	s := hanatest.NewUnstartedServer()
+	s.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: pool}
+	s.StartTLS()
+	defer s.Close()
+
+	// basic auth is required without certificate
+	s.SetCredential("BOB", "secret")
+	s.WriteFile("/pkg/a.txt", []byte("hello"))
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, &pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+
+	// without password
+	uri := s.ClientURL("/pkg")
+	uri.User = nil
+
+	if _, err := hana.NewClientWithOptions(uri, &hana.ClientOptions{CAFile: caFile}); err == nil {
+		t.Error("request without credential should be rejected")
+	}
+
+	if _, err := hana.NewClientWithOptions(uri, &hana.ClientOptions{CAFile: caFile, CertFile: certFile}); err == nil {
+		t.Error("client certificate without key should be rejected")
+	}
+
+	c, err := hana.NewClientWithOptions(uri, &hana.ClientOptions{CAFile: caFile, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Lock("/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if holder := s.LockedBy("/pkg/a.txt"); holder != "ALICE" {
+		t.Errorf("locked by %q, want the common name of certificate", holder)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	s := hanatest.NewServer()
+	defer s.Close()
+
+	s.WriteFile("/pkg/a.txt", []byte("hello"))
+
+	requests := int32(0)
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := parseProxyAuthorization(r); !ok || user != "proxy" || password != "secret" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		s.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("proxy", "secret")
+
+	// only resolvable by proxy
+	uri := s.ClientURL("/pkg")
+	uri.Host = "hana.invalid"
+
+	c, err := hana.NewClientWithOptions(uri, &hana.ClientOptions{Proxy: proxyURL.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rt, err := c.ProxyURL(); err != nil || rt == nil || rt.Host != proxyURL.Host {
+		t.Errorf("proxy %v, %v", rt, err)
+	}
+
+	if content, err := c.ReadFile("/a.txt"); err != nil || string(content) != "hello" {
+		t.Errorf("read %q, %v", content, err)
+	}
+
+	if n := atomic.LoadInt32(&requests); n == 0 {
+		t.Error("requests should be sent to proxy")
+	}
+
+	for _, invalid := range []string{"proxy.example.com:8080", "http://", "://x"} {
+		if _, err := hana.NewClientWithOptions(uri, &hana.ClientOptions{Proxy: invalid}); err == nil {
+			t.Errorf("invalid proxy '%v' should be rejected", invalid)
+		}
+	}
+}
+
+// parseProxyAuthorization of basic authentication
+func parseProxyAuthorization(r *http.Request) (string, string, bool) {
+	r = &http.Request{Header: http.Header{"Authorization": r.Header["Proxy-Authorization"]}}
+	return r.BasicAuth()
+}
@@ -0,0 +1,57 @@
+package hana
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestShouldRetry(t *testing.T) {
+	cases := []struct {
+		name  string
+		err   error
+		retry bool
+	}{
+		{"nil", nil, false},
+		{"other", errors.New("x"), false},
+		{"server error", &RequestError{Kind: KindServerError, StatusCode: 503}, true},
+		{"gateway timeout", &RequestError{Kind: KindTimeout, StatusCode: 504}, true},
+		{"not found", &RequestError{Kind: KindNotFound, StatusCode: 404}, false},
+		{"precondition failed", &RequestError{Kind: KindPreconditionFailed, StatusCode: 412}, false},
+		{"client timeout", newTransportError("GET", "/", timeoutError{}), false},
+		{"connection reset", newTransportError("GET", "/", &net.OpError{Op: "read", Err: syscall.ECONNRESET}), true},
+	}
+
+	for _, c := range cases {
+		if rt := shouldRetry(c.err); rt != c.retry {
+			t.Errorf("%v: retry %v, want %v", c.name, rt, c.retry)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{retryBackoff: 100 * time.Millisecond}
+
+	for attempt := 0; attempt < 4; attempt++ {
+		wait := c.retryBackoff << uint(attempt)
+		for i := 0; i < 20; i++ {
+			if d := c.backoff(attempt); d < wait/2 || d > wait {
+				t.Errorf("attempt %v: backoff %v not in [%v, %v]", attempt, d, wait/2, wait)
+			}
+		}
+	}
+
+	c.retryBackoff = 0
+
+	if d := c.backoff(1); d != 0 {
+		t.Errorf("no backoff expected, got %v", d)
+	}
+}
@@ -10,5 +10,11 @@ type PathStat struct {
 	SymbolicLink bool
 	Activated    bool
 	TimeStamp    int64
+	// Size of file content, negative if unknown
 	Size         int64
+	ETag         string
+	ActivatedBy  string
+	Version      int64
+	ObjectStatus string
+	ContentType  string
 }
@@ -71,6 +71,8 @@ type Child struct {
 	Attributes       Attributes    `json:"Attributes"`
 	Workspaces       []interface{} `json:"Workspaces"`
 	RunLocation      string        `json:"RunLocation"`
+	// Length of file content, if provided by server
+	Length *int64 `json:"Length,omitempty"`
 	// Children file or directory
 	Children []Child `json:"Children"`
 	// for file, sap back pack is different
@@ -1,8 +1,20 @@
 package hana
 
-import "encoding/base64"
+import (
+	"encoding/base64"
+	"net/url"
+)
 
 func basicAuth(username, password string) string {
 	auth := username + ":" + password
 	return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
 }
+
+// redactURL hide the password of url, for logging
+func redactURL(u *url.URL) string {
+	rt := *u
+	if _, exist := rt.User.Password(); exist {
+		rt.User = url.UserPassword(rt.User.Username(), "xxxxx")
+	}
+	return rt.String()
+}